@@ -5,6 +5,8 @@ go 1.24.2
 require (
 	golang.ngrok.com/ngrok v1.13.0
 	golang.org/x/mobile v0.0.0-20251021151156-188f512ec823
+	golang.org/x/text v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -25,5 +27,4 @@ require (
 	golang.org/x/term v0.36.0 // indirect
 	google.golang.org/protobuf v1.35.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
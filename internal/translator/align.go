@@ -0,0 +1,343 @@
+package translator
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Alignment of a translation
+// Every stage records which runes of its input became which runes of its output as a list of cuts,
+// points (source, target) splitting both texts into aligned segments. The replacement engine
+// records its matches; the other stages change few runes, so their cuts come from a diff.
+// Composing the cuts of all stages gives the alignment of the whole translation
+
+// Span maps source runes [SourceStart, SourceEnd) to the target runes [TargetStart, TargetEnd) they became
+// Either side is empty for runes that were only removed or only added
+type Span struct {
+	SourceStart int `json:"source_start"`
+	SourceEnd   int `json:"source_end"`
+	TargetStart int `json:"target_start"`
+	TargetEnd   int `json:"target_end"`
+}
+
+// Alignment is a translation together with the spans linking its source and target
+// Spans are in order and cover both texts, so they are sorted by source and by target alike
+// Offsets are in runes; each byte of invalid UTF-8 counts as one rune
+type Alignment struct {
+	Direction Direction `json:"direction"`
+	Source    string    `json:"source"`
+	Target    string    `json:"target"`
+	Spans     []Span    `json:"spans"`
+}
+
+// TranslateWithAlignment translates input in the given direction and aligns the result with it
+func TranslateWithAlignment(input string, direction Direction) Alignment {
+	alignment, _ := defaultTranslator.TranslateWithAlignmentContext(context.Background(), input, direction)
+	return alignment
+}
+
+// TranslateWithAlignmentContext translates input in the given direction and aligns the result with it,
+// returning ctx.Err() if cancelled between stages
+func (t *Translator) TranslateWithAlignmentContext(ctx context.Context, input string, direction Direction) (Alignment, error) {
+	cuts := identityAlignment(utf8.RuneCountInString(input))
+	var result string
+	var err error
+	if direction == DirectionFromPejelagarto {
+		result, err = t.fromPejelagarto(ctx, input, &cuts)
+	} else {
+		result, err = t.toPejelagarto(ctx, input, &cuts)
+	}
+	if err != nil {
+		return Alignment{}, err
+	}
+
+	spans := make([]Span, 0, len(cuts))
+	for i := 1; i < len(cuts); i++ {
+		spans = append(spans, Span{cuts[i-1].source, cuts[i].source, cuts[i-1].target, cuts[i].target})
+	}
+	return Alignment{Direction: direction, Source: input, Target: result, Spans: spans}, nil
+}
+
+// TargetRange returns the target runes that source runes [start, end) became
+func (a Alignment) TargetRange(start, end int) (int, int) {
+	first := sort.Search(len(a.Spans), func(i int) bool { return a.Spans[i].SourceEnd > start })
+	targetStart, targetEnd := -1, -1
+	for i := first; i < len(a.Spans) && a.Spans[i].SourceStart < end; i++ {
+		span := a.Spans[i]
+		// Runes added between the source runes belong to them, runes added at their edges do not
+		if span.SourceStart == span.SourceEnd && span.SourceStart <= start {
+			continue
+		}
+		if targetStart < 0 {
+			targetStart = span.TargetStart
+		}
+		targetEnd = span.TargetEnd
+	}
+	if targetStart < 0 {
+		// Nothing was aligned with the range: point at where it would be
+		if first < len(a.Spans) {
+			return a.Spans[first].TargetStart, a.Spans[first].TargetStart
+		}
+		target := utf8.RuneCountInString(a.Target)
+		return target, target
+	}
+	return targetStart, targetEnd
+}
+
+// SourceRange returns the source runes that became target runes [start, end)
+func (a Alignment) SourceRange(start, end int) (int, int) {
+	reversed := Alignment{Source: a.Target, Target: a.Source, Spans: make([]Span, len(a.Spans))}
+	for i, span := range a.Spans {
+		reversed.Spans[i] = Span{span.TargetStart, span.TargetEnd, span.SourceStart, span.SourceEnd}
+	}
+	return reversed.TargetRange(start, end)
+}
+
+// Gloss renders an interlinear gloss: every source line word by word, each word above the target text it became
+// Line pairs are separated by an empty line
+func (a Alignment) Gloss() string {
+	source := []rune(strings.ToValidUTF8(a.Source, "�"))
+	target := []rune(a.Target)
+
+	var blocks []string
+	lineStart := 0
+	for lineStart <= len(source) {
+		lineEnd := lineStart
+		for lineEnd < len(source) && source[lineEnd] != '\n' {
+			lineEnd++
+		}
+
+		var sourceLine, targetLine strings.Builder
+		for i := lineStart; i < lineEnd; {
+			if unicode.IsSpace(source[i]) {
+				i++
+				continue
+			}
+			wordEnd := i
+			for wordEnd < lineEnd && !unicode.IsSpace(source[wordEnd]) {
+				wordEnd++
+			}
+			targetStart, targetEnd := a.TargetRange(i, wordEnd)
+			word := string(source[i:wordEnd])
+			gloss := strings.TrimSpace(strings.ReplaceAll(string(target[targetStart:targetEnd]), "\n", " "))
+			if gloss == "" {
+				gloss = "-"
+			}
+
+			width := max(utf8.RuneCountInString(word), utf8.RuneCountInString(gloss))
+			if sourceLine.Len() > 0 {
+				sourceLine.WriteString("  ")
+				targetLine.WriteString("  ")
+			}
+			sourceLine.WriteString(word + strings.Repeat(" ", width-utf8.RuneCountInString(word)))
+			targetLine.WriteString(gloss + strings.Repeat(" ", width-utf8.RuneCountInString(gloss)))
+			i = wordEnd
+		}
+		if sourceLine.Len() > 0 {
+			blocks = append(blocks, strings.TrimRight(sourceLine.String(), " ")+"\n"+strings.TrimRight(targetLine.String(), " "))
+		}
+		lineStart = lineEnd + 1
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// alignCut is a point splitting the source and the target of a stage into aligned segments
+type alignCut struct {
+	source, target int
+}
+
+// alignment is the list of cuts of a stage, from (0, 0) to the lengths of both texts
+type alignment []alignCut
+
+// identityAlignment aligns a text of n runes with itself rune by rune
+func identityAlignment(n int) alignment {
+	cuts := make(alignment, n+1)
+	for i := range cuts {
+		cuts[i] = alignCut{i, i}
+	}
+	return cuts
+}
+
+// composeAlignments aligns the source of first with the target of second, first's target being second's source
+// Runes removed by first and added by second at the same place are aligned with each other
+func composeAlignments(first, second alignment) alignment {
+	var cuts alignment
+	i, j := 0, 0
+	for i < len(first) && j < len(second) {
+		switch {
+		case first[i].target < second[j].source:
+			i++
+		case first[i].target > second[j].source:
+			j++
+		default:
+			middle := first[i].target
+			lastI, lastJ := i, j
+			for lastI+1 < len(first) && first[lastI+1].target == middle {
+				lastI++
+			}
+			for lastJ+1 < len(second) && second[lastJ+1].source == middle {
+				lastJ++
+			}
+			cuts = appendCut(cuts, alignCut{first[i].source, second[j].target})
+			cuts = appendCut(cuts, alignCut{first[lastI].source, second[lastJ].target})
+			i, j = lastI+1, lastJ+1
+		}
+	}
+	return cuts
+}
+
+// appendCut appends a cut unless it repeats the last one
+func appendCut(cuts alignment, cut alignCut) alignment {
+	if len(cuts) > 0 && cuts[len(cuts)-1] == cut {
+		return cuts
+	}
+	return append(cuts, cut)
+}
+
+// diffAlignmentLimit bounds the edit distance diffAlignment searches, beyond it a changed region is one segment
+const diffAlignmentLimit = 1024
+
+// diffAlignment aligns two versions of a text that differ in few places, e.g. escapes, digits or timestamps
+// Stages aligned this way keep line breaks, so when both have as many lines they are aligned line by line
+func diffAlignment(source, target string) alignment {
+	sourceRunes, targetRunes := []rune(source), []rune(target)
+	cuts := alignment{{0, 0}}
+
+	sourceLines := strings.Count(source, "\n")
+	if sourceLines == 0 || sourceLines != strings.Count(target, "\n") {
+		return diffRunes(cuts, sourceRunes, targetRunes, 0, 0)
+	}
+
+	sourceStart, targetStart := 0, 0
+	for sourceStart <= len(sourceRunes) {
+		sourceEnd := sourceStart
+		for sourceEnd < len(sourceRunes) && sourceRunes[sourceEnd] != '\n' {
+			sourceEnd++
+		}
+		targetEnd := targetStart
+		for targetEnd < len(targetRunes) && targetRunes[targetEnd] != '\n' {
+			targetEnd++
+		}
+		cuts = diffRunes(cuts, sourceRunes[sourceStart:sourceEnd], targetRunes[targetStart:targetEnd], sourceStart, targetStart)
+		if sourceEnd < len(sourceRunes) {
+			cuts = appendCut(cuts, alignCut{sourceEnd + 1, targetEnd + 1})
+		}
+		sourceStart, targetStart = sourceEnd+1, targetEnd+1
+	}
+	return cuts
+}
+
+// diffRunes appends the cuts aligning source and target, found at the given offsets, to cuts
+func diffRunes(cuts alignment, source, target []rune, sourceOffset, targetOffset int) alignment {
+	prefix := 0
+	for prefix < len(source) && prefix < len(target) && source[prefix] == target[prefix] {
+		prefix++
+		cuts = appendCut(cuts, alignCut{sourceOffset + prefix, targetOffset + prefix})
+	}
+	suffix := 0
+	for suffix < len(source)-prefix && suffix < len(target)-prefix &&
+		source[len(source)-1-suffix] == target[len(target)-1-suffix] {
+		suffix++
+	}
+
+	middleSource, middleTarget := source[prefix:len(source)-suffix], target[prefix:len(target)-suffix]
+	sourceOffset, targetOffset = sourceOffset+prefix, targetOffset+prefix
+	switch {
+	case len(middleSource) == len(middleTarget):
+		// Same length, e.g. changed digits: rune by rune
+		for i := 1; i <= len(middleSource); i++ {
+			cuts = appendCut(cuts, alignCut{sourceOffset + i, targetOffset + i})
+		}
+	case len(middleSource) == 0 || len(middleTarget) == 0:
+		cuts = appendCut(cuts, alignCut{sourceOffset + len(middleSource), targetOffset + len(middleTarget)})
+	default:
+		for _, cut := range myersCuts(middleSource, middleTarget, diffAlignmentLimit) {
+			cuts = appendCut(cuts, alignCut{sourceOffset + cut.source, targetOffset + cut.target})
+		}
+	}
+
+	sourceOffset, targetOffset = sourceOffset+len(middleSource), targetOffset+len(middleTarget)
+	for i := 1; i <= suffix; i++ {
+		cuts = appendCut(cuts, alignCut{sourceOffset + i, targetOffset + i})
+	}
+	return cuts
+}
+
+// myersCuts finds a shortest edit script from a to b (Myers' algorithm) and returns its cuts after (0, 0)
+// Consecutive insertions and deletions are merged into one segment; beyond limit edits the whole
+// of a is aligned with the whole of b
+func myersCuts(a, b []rune, limit int) alignment {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int // trace[d] holds v[-d..d] before step d
+
+	found := false
+	for d := 0; d <= n+m && d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return alignment{{n, m}}
+	}
+
+	// Walk back from (n, m), collecting the points of the path
+	path := alignment{{n, m}}
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d]
+		at := func(k int) int { return previous[k+d] }
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := at(previousK)
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			x, y = x-1, y-1
+			path = append(path, alignCut{x, y})
+		}
+		x, y = previousX, previousY
+		path = append(path, alignCut{x, y})
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		path = append(path, alignCut{x, y})
+	}
+
+	// path runs backwards from (n, m) to (0, 0); keep the points next to a rune-for-rune step
+	var cuts alignment
+	for i := len(path) - 2; i >= 0; i-- {
+		before := path[i+1]
+		cut := path[i]
+		stepIn := cut.source-before.source == 1 && cut.target-before.target == 1
+		stepOut := i == 0 || (path[i-1].source-cut.source == 1 && path[i-1].target-cut.target == 1)
+		if stepIn || stepOut {
+			cuts = append(cuts, cut)
+		}
+	}
+	return cuts
+}
@@ -0,0 +1,92 @@
+package translator
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzAlignment tests that alignments cover both texts in order and do not change the translation
+func FuzzAlignment(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("")
+	f.Add("Hello, World! 123\nshell chat's 'quoted'")
+	f.Add("2025-10-19T14:30:45Z\nten -42 'ecks'\n2025-10-19T14:30:45+05:30")
+	f.Add("\xff invalid \xfe")
+	f.Fuzz(func(t *testing.T, input string) {
+		tr := fixedTestTranslator(Options{})
+
+		forward, err := tr.TranslateWithAlignmentContext(t.Context(), input, DirectionToPejelagarto)
+		if err != nil {
+			t.Fatalf("TranslateWithAlignmentContext() error: %v", err)
+		}
+		if want := tr.ToPejelagarto(input); forward.Target != want {
+			t.Fatalf("aligned translation differs\nAligned: %q\nPlain:   %q", forward.Target, want)
+		}
+		checkSpans(t, forward)
+
+		backward, err := tr.TranslateWithAlignmentContext(t.Context(), forward.Target, DirectionFromPejelagarto)
+		if err != nil {
+			t.Fatalf("TranslateWithAlignmentContext() error: %v", err)
+		}
+		if want := tr.FromPejelagarto(forward.Target); backward.Target != want {
+			t.Fatalf("aligned translation differs\nAligned: %q\nPlain:   %q", backward.Target, want)
+		}
+		checkSpans(t, backward)
+		_ = backward.Gloss()
+	})
+}
+
+// checkSpans fails unless the spans of a are contiguous and cover its source and target
+func checkSpans(t *testing.T, a Alignment) {
+	t.Helper()
+	source, target := 0, 0
+	for _, span := range a.Spans {
+		if span.SourceStart != source || span.TargetStart != target || span.SourceEnd < source || span.TargetEnd < target {
+			t.Fatalf("span %+v does not follow (%d, %d)\nSource: %q\nTarget: %q", span, source, target, a.Source, a.Target)
+		}
+		if span.SourceStart == span.SourceEnd && span.TargetStart == span.TargetEnd {
+			t.Fatalf("empty span %+v", span)
+		}
+		source, target = span.SourceEnd, span.TargetEnd
+	}
+	if source != len([]rune(a.Source)) || target != len([]rune(a.Target)) {
+		t.Fatalf("spans end at (%d, %d), want (%d, %d)", source, target, utf8.RuneCountInString(a.Source), utf8.RuneCountInString(a.Target))
+	}
+}
+
+// TestAlignmentRanges verifies words map to the words they became and back
+func TestAlignmentRanges(t *testing.T) {
+	tr := New(Options{DisableTimestamp: true})
+	a, err := tr.TranslateWithAlignmentContext(t.Context(), "Hello world, 42 cats\nshell chat", DirectionToPejelagarto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := tr.ToPejelagarto(a.Source); a.Target != want {
+		t.Fatalf("Target = %q, want %q", a.Target, want)
+	}
+
+	target := []rune(a.Target)
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{0, 5, "'aŔaKa"},
+		{6, 12, "Eìǩgf،"},
+		{13, 15, "52"},
+		{21, 26, "'xš'lèg"},
+	}
+	for _, tt := range tests {
+		start, end := a.TargetRange(tt.start, tt.end)
+		if got := string(target[start:end]); got != tt.want {
+			t.Errorf("TargetRange(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+		if sourceStart, sourceEnd := a.SourceRange(start, end); sourceStart != tt.start || sourceEnd != tt.end {
+			t.Errorf("SourceRange(%d, %d) = %d, %d, want %d, %d", start, end, sourceStart, sourceEnd, tt.start, tt.end)
+		}
+	}
+
+	want := "Hello   world,  42  cats\n'aŔaKa  Eìǩgf،  52  cutS\n\nshell    chat\n'xš'lèg  'jcUt"
+	if got := a.Gloss(); got != want {
+		t.Errorf("Gloss() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package translator
+
+import (
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Consonant wheels
+// A second stage moves consonants along their own wheels of diacritic forms, driven by the digits
+// of the cluster count in base 3, least significant first: the kth digit moves the consonant at
+// the kth triangular number (1st, 3rd, 6th, 10th, ...) that many steps
+// Every form is one rune of the same letter as its base, so the cluster count does not change, a
+// letter never turns into another one and the letters the maps keep apart (LetterMap outputs avoid
+// the conjunction letters) stay apart
+
+// ConsonantWheel holds the diacritic forms of each consonant, the base consonant first
+var ConsonantWheel = map[rune][]string{
+	'b': {"b", "ḃ", "ḅ"},
+	'c': {"c", "ç", "č", "ć", "ĉ"},
+	'd': {"d", "ď", "ḍ", "ḏ"},
+	'f': {"f", "ḟ"},
+	'g': {"g", "ğ", "ǧ", "ĝ", "ģ"},
+	'h': {"h", "ĥ", "ḧ", "ḥ"},
+	'j': {"j", "ĵ"},
+	'k': {"k", "ķ", "ǩ", "ḱ"},
+	'l': {"l", "ľ", "ĺ", "ļ"},
+	'm': {"m", "ḿ", "ṁ", "ṃ"},
+	'n': {"n", "ñ", "ň", "ń", "ņ"},
+	'p': {"p", "ṕ", "ṗ"},
+	'r': {"r", "ř", "ŕ", "ŗ"},
+	's': {"s", "š", "ś", "ş", "ŝ"},
+	't': {"t", "ť", "ţ", "ṭ"},
+	'v': {"v", "ṽ", "ṿ"},
+	'x': {"x", "ẍ", "ẋ"},
+	'z': {"z", "ž", "ź", "ż", "ẑ"},
+}
+
+// consonantWheels holds the consonant wheels of a dialect
+type consonantWheels struct {
+	wheels map[rune][]string
+	bases  map[string]rune // base consonant of every form
+}
+
+// newConsonantWheels indexes the consonant wheels of a dialect
+func newConsonantWheels(wheels map[rune][]string) consonantWheels {
+	w := consonantWheels{wheels: wheels, bases: make(map[string]rune)}
+	for base, forms := range wheels {
+		for _, form := range forms {
+			w.bases[form] = base
+		}
+	}
+	return w
+}
+
+// consonantSteps returns the steps of the consonants the stage moves for a cluster count,
+// keyed by the 0-based index of the consonant
+func consonantSteps(count int) map[int]int {
+	steps := make(map[int]int)
+	for k := 1; count > 0; k++ {
+		if digit := count % 3; digit > 0 {
+			steps[k*(k+1)/2-1] = digit
+		}
+		count /= 3
+	}
+	return steps
+}
+
+// applyConsonantReplacementLogicToPejelagarto moves consonants along their wheels
+func applyConsonantReplacementLogicToPejelagarto(input string) string {
+	return withCurrentRules(input, func(c *compiledRules, input string) string { return c.consonants.applyToPejelagarto(input) })
+}
+
+// applyConsonantReplacementLogicFromPejelagarto moves consonants back along their wheels
+func applyConsonantReplacementLogicFromPejelagarto(input string) string {
+	return withCurrentRules(input, func(c *compiledRules, input string) string { return c.consonants.applyFromPejelagarto(input) })
+}
+
+// applyToPejelagarto moves consonants along their wheels
+func (w consonantWheels) applyToPejelagarto(input string) string {
+	return w.apply(input, 1)
+}
+
+// applyFromPejelagarto moves consonants back along their wheels
+func (w consonantWheels) applyFromPejelagarto(input string) string {
+	return w.apply(input, -1)
+}
+
+// apply moves the consonants selected by consonantSteps forward (direction 1) or backward (direction -1)
+func (w consonantWheels) apply(input string, direction int) string {
+	if !utf8.ValidString(input) || len(w.wheels) == 0 {
+		return input
+	}
+
+	runes := []rune(input)
+	starts := accentClusters(runes)
+	steps := consonantSteps(len(starts))
+
+	result := make([]rune, len(runes))
+	copy(result, runes)
+	consonant := 0
+	for i, start := range starts {
+		// Forms are single runes, a consonant followed by combining marks is left alone
+		end := len(runes)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		if end != start+1 {
+			continue
+		}
+		r := runes[start]
+		lower := unicode.ToLower(r)
+		if unicode.IsUpper(r) && unicode.ToUpper(lower) != r {
+			continue
+		}
+		base, ok := w.bases[string(lower)]
+		if !ok {
+			continue
+		}
+		if step, ok := steps[consonant]; ok {
+			wheel := w.wheels[base]
+			newIndex := (slices.Index(wheel, string(lower)) + direction*step) % len(wheel)
+			if newIndex < 0 {
+				newIndex += len(wheel)
+			}
+			newRune, _ := utf8.DecodeRuneInString(wheel[newIndex])
+			if unicode.IsUpper(r) {
+				// Wheels only hold forms whose case conversion is reversible
+				newRune = unicode.ToUpper(newRune)
+			}
+			result[start] = newRune
+		}
+		consonant++
+	}
+	return string(result)
+}
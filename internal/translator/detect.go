@@ -0,0 +1,182 @@
+package translator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Direction detection
+// Each signal adds evidence, in log-odds, that a text is Pejelagarto (positive) or Human (negative)
+// The sum decides the direction and its logistic transform the confidence
+
+// Direction is the way a text should be translated
+type Direction string
+
+const (
+	// DirectionToPejelagarto translates Human text to Pejelagarto
+	DirectionToPejelagarto Direction = "to"
+	// DirectionFromPejelagarto translates Pejelagarto text back to Human
+	DirectionFromPejelagarto Direction = "from"
+)
+
+// Signal weights, in log-odds
+const (
+	timestampCompleteWeight = 4.0  // day, month and year characters all present
+	timestampPartialWeight  = 1.5  // some timestamp characters present
+	timestampMissingWeight  = -2.0 // no timestamp characters at all
+	escapeWeight            = 1.0  // soft hyphen escaping a quote, another soft hyphen or the punctuation of a number
+	danglingEscapeWeight    = -1.0 // soft hyphen escaping anything else
+	punctuationWeight       = 2.0  // scaled by the balance of Pejelagarto vs Human punctuation
+	accentWeight            = 0.5  // per vowel selected by the prime factors
+)
+
+// Signal is one piece of evidence used by DetectDirection
+type Signal struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"` // log-odds contribution, positive for Pejelagarto
+	Detail string  `json:"detail"`
+}
+
+// Detection is the result of DetectDirection
+type Detection struct {
+	Direction  Direction `json:"direction"`
+	Confidence float64   `json:"confidence"` // from 0.5 (no evidence) to 1
+	Signals    []Signal  `json:"signals"`
+}
+
+// DetectDirection scores how likely input is Pejelagarto and picks the translation direction
+func DetectDirection(input string) Detection {
+	return defaultTranslator.DetectDirection(input)
+}
+
+// TranslateAuto translates input in the detected direction
+func TranslateAuto(input string) (string, Detection) {
+	result, detection, _ := defaultTranslator.TranslateAutoContext(context.Background(), input)
+	return result, detection
+}
+
+// DetectDirection scores how likely input is Pejelagarto produced with the translator's options
+// Signals of disabled stages are skipped
+func (t *Translator) DetectDirection(input string) Detection {
+	input = strings.ToValidUTF8(input, "")
+	runes := []rune(input)
+	var signals []Signal
+
+	specialChars := timestampSpecialChars()
+	hidden, lossless := hiddenTimestampMask(runes)
+	if !t.opts.DisableTimestamp {
+		components := timestampComponents()
+		present := make([]bool, len(components))
+		found := 0
+		for i, r := range runes {
+			if component := specialChars[r]; hidden[i] && !present[component] {
+				present[component] = true
+				found++
+			}
+		}
+		switch {
+		case present[0] && present[1] && present[2]:
+			signals = append(signals, Signal{"timestamp", timestampCompleteWeight, "day, month and year characters present"})
+		case found > 0:
+			signals = append(signals, Signal{"timestamp", timestampPartialWeight, fmt.Sprintf("%d of %d timestamp components present", found, len(components))})
+		default:
+			signals = append(signals, Signal{"timestamp", timestampMissingWeight, "no timestamp characters"})
+		}
+	}
+
+	// The remaining signals look at the text as the decoder sees it
+	stripped := make([]rune, 0, len(runes))
+	for i, r := range runes {
+		if !hidden[i] || t.opts.DisableTimestamp {
+			stripped = append(stripped, r)
+		}
+	}
+
+	if !t.opts.DisablePunctuation || !t.opts.DisableMapReplacements {
+		// Lossless texts also escape the timestamp characters of the Human text
+		isSpecial := func(r rune) bool { _, special := specialChars[r]; return special }
+		escapes, dangling := 0, 0
+		for i := 0; i < len(stripped); i++ {
+			if stripped[i] != OutputEscapeChar {
+				continue
+			}
+			if i+1 < len(stripped) && (stripped[i+1] == '\'' || stripped[i+1] == OutputEscapeChar || isNumberPunctuation(stripped[i+1]) ||
+				lossless && isSpecial(stripped[i+1])) {
+				escapes++
+				i++
+			} else {
+				dangling++
+			}
+		}
+		if escapes > 0 {
+			signals = append(signals, Signal{"escapes", escapeWeight, fmt.Sprintf("%d soft hyphen escapes", escapes)})
+		}
+		if dangling > 0 {
+			signals = append(signals, Signal{"escapes", danglingEscapeWeight, fmt.Sprintf("%d dangling soft hyphens", dangling)})
+		}
+	}
+
+	// An invalid dialect leaves only the signals that do not depend on it
+	rules, err := t.decodingRules(input)
+	if !t.opts.DisablePunctuation && err == nil {
+		text := string(stripped)
+		pejelagarto, human := 0, 0
+		for key, value := range rules.punctuation {
+			if utf8.RuneCountInString(value) > 1 {
+				value = "'" + value
+			}
+			pejelagarto += strings.Count(text, value)
+			// Multi-rune values contain their own key (e.g. "." in "'.."), count it only outside them
+			human += strings.Count(strings.ReplaceAll(text, value, ""), key)
+		}
+		if total := pejelagarto + human; total > 0 {
+			weight := punctuationWeight * float64(pejelagarto-human) / float64(total)
+			signals = append(signals, Signal{"punctuation", weight, fmt.Sprintf("%d Pejelagarto and %d Human punctuation marks", pejelagarto, human)})
+		}
+	}
+
+	if !t.opts.DisableAccents && err == nil {
+		accented, unaccented := 0, 0
+		for _, vowel := range factorVowelsLocally(stripped, rules.wheels, t.locality(input), rules.sentenceTerminators()) {
+			if vowel.accented {
+				accented++
+			} else {
+				unaccented++
+			}
+		}
+		if accented+unaccented > 0 {
+			weight := accentWeight * float64(accented-unaccented)
+			signals = append(signals, Signal{"accents", weight, fmt.Sprintf("%d of %d wheel-selected vowels accented", accented, accented+unaccented)})
+		}
+	}
+
+	score := 0.0
+	for _, signal := range signals {
+		score += signal.Weight
+	}
+	detection := Detection{
+		Direction:  DirectionToPejelagarto,
+		Confidence: 1 / (1 + math.Exp(-math.Abs(score))),
+		Signals:    signals,
+	}
+	if score > 0 {
+		detection.Direction = DirectionFromPejelagarto
+	}
+	return detection
+}
+
+// TranslateAutoContext translates input in the detected direction, returning ctx.Err() if cancelled between stages
+func (t *Translator) TranslateAutoContext(ctx context.Context, input string) (string, Detection, error) {
+	detection := t.DetectDirection(input)
+	var result string
+	var err error
+	if detection.Direction == DirectionFromPejelagarto {
+		result, err = t.FromPejelagartoContext(ctx, input)
+	} else {
+		result, err = t.ToPejelagartoContext(ctx, input)
+	}
+	return result, detection, err
+}
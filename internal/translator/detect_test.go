@@ -0,0 +1,54 @@
+package translator
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzDetectTranslatorOutput tests that translator output is always detected as Pejelagarto
+func FuzzDetectTranslatorOutput(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("")
+	f.Add("Hello, World! How are you?")
+	f.Add("¿Dónde está la biblioteca? ‽‽‽ ¡¡¡")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		pejelagarto := TranslateToPejelagarto(input)
+		if detection := DetectDirection(pejelagarto); detection.Direction != DirectionFromPejelagarto {
+			t.Errorf("translator output detected as %q (confidence %.2f)\nInput:       %q\nPejelagarto: %q\nSignals: %+v",
+				detection.Direction, detection.Confidence, input, pejelagarto, detection.Signals)
+		}
+	})
+}
+
+// TestDetectDirection verifies Human text is detected as Human and TranslateAuto round-trips it
+func TestDetectDirection(t *testing.T) {
+	inputs := []string{
+		"hello world",
+		"The quick brown fox jumps over the lazy dog.",
+		"Is this working? Yes, it is: (mostly) fine!",
+		"Él comió una manzana; después, durmió.",
+	}
+
+	for _, input := range inputs {
+		detection := DetectDirection(input)
+		if detection.Direction != DirectionToPejelagarto {
+			t.Errorf("DetectDirection(%q) = %q, want %q\nSignals: %+v", input, detection.Direction, DirectionToPejelagarto, detection.Signals)
+		}
+		if detection.Confidence < 0.5 || detection.Confidence > 1 {
+			t.Errorf("DetectDirection(%q) confidence = %v, want within [0.5, 1]", input, detection.Confidence)
+		}
+
+		pejelagarto, _ := TranslateAuto(input)
+		human, detection := TranslateAuto(pejelagarto)
+		if detection.Direction != DirectionFromPejelagarto {
+			t.Errorf("TranslateAuto(%q) detected %q, want %q", pejelagarto, detection.Direction, DirectionFromPejelagarto)
+		}
+		if expected, _ := removeISO8601timestamp(human); expected != input {
+			t.Errorf("TranslateAuto round-trip = %q, want %q", expected, input)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package translator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Private dialects
+// GenerateRuleset derives a whole dialect from a passphrase, so a group sharing the passphrase
+// reads text that the built-in dialect cannot. This hides text from casual readers; it is not encryption:
+// the letter pairs survive frequency analysis and the timestamp is still readable
+
+// TranslateToPejelagartoWithKey translates Human text to the private dialect of key
+// It returns an empty string when no dialect can be generated from key
+func TranslateToPejelagartoWithKey(input string, key string) string {
+	return keyTranslator(key).ToPejelagarto(input)
+}
+
+// TranslateFromPejelagartoWithKey translates text in the private dialect of key back to Human
+// It returns an empty string when no dialect can be generated from key
+func TranslateFromPejelagartoWithKey(input string, key string) string {
+	return keyTranslator(key).FromPejelagarto(input)
+}
+
+// keyTranslator returns a translator of the private dialect of key, one that translates nothing
+// when GenerateRuleset fails
+func keyTranslator(key string) *Translator {
+	rs, err := GenerateRuleset(key)
+	if err != nil {
+		return &Translator{err: err}
+	}
+	return New(Options{Ruleset: rs})
+}
+
+const (
+	// maxGenerateRulesetAttempts bounds the dialects GenerateRuleset draws for one passphrase
+	maxGenerateRulesetAttempts = 32
+	// maxConjunctionDraws bounds the letters drawn for the conjunction values of one script
+	maxConjunctionDraws = 10000
+)
+
+// GenerateRuleset derives a valid dialect from a passphrase; the same passphrase always gives the same dialect
+// It starts from the built-in dialect and keeps its documented constraints:
+//   - letters form true bijective pairs within their script, vowels with vowels and consonants with consonants
+//   - conjunctions keep their keys and get new values of the same rune length, written only with
+//     letters outside the letter map, without repeated neighbours and none starting another
+//   - punctuation keys get a permutation of the built-in targets
+//   - accent and consonant wheels keep their forms in a new order, the base letter staying first
+//
+// The escape characters are those of the current dialect
+// Every dialect is checked with ValidateStrict and round-tripped over generatedRulesetCorpus before it is returned;
+// one that fails is drawn again from the passphrase and the number of the attempt, and an error is
+// returned when maxGenerateRulesetAttempts dialects all fail
+func GenerateRuleset(passphrase string) (*Ruleset, error) {
+	var err error
+	for attempt := 0; attempt < maxGenerateRulesetAttempts; attempt++ {
+		seed := []byte(passphrase)
+		if attempt > 0 {
+			seed = fmt.Appendf(seed, "\x00%d", attempt)
+		}
+		var rs *Ruleset
+		if rs, err = generateRuleset(seed); err == nil && generatedRulesetRoundTrips(rs) {
+			return rs, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no valid dialect in %d attempts: %w", maxGenerateRulesetAttempts, err)
+	}
+	return nil, fmt.Errorf("no valid dialect in %d attempts", maxGenerateRulesetAttempts)
+}
+
+// generateRuleset draws a dialect from a seed, see GenerateRuleset
+func generateRuleset(seed []byte) (*Ruleset, error) {
+	sum := sha256.Sum256(seed)
+	rng := rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(sum[:8]))))
+
+	rs := DefaultRuleset()
+	rs.Name = "generated"
+	rs.InternalEscapeChar = InternalEscapeChar
+	rs.OutputEscapeChar = OutputEscapeChar
+
+	// Latin maps are drawn first and the other scripts last, so that adding a script does not change
+	// the Latin maps, punctuation and wheels a passphrase already gave
+	latin := sourceScripts[0]
+	var err error
+	if rs.ConjunctionMap, rs.LetterMap, err = generateScriptMaps(rng, latin, rs.ConjunctionMap, rs.LetterMap); err != nil {
+		return nil, err
+	}
+
+	// Punctuation: the same targets, dealt to other keys
+	keys := sortedKeys(rs.PunctuationMap)
+	targets := make([]string, 0, len(keys))
+	for _, key := range keys {
+		targets = append(targets, rs.PunctuationMap[key])
+	}
+	sort.Strings(targets)
+	rng.Shuffle(len(targets), func(i, j int) { targets[i], targets[j] = targets[j], targets[i] })
+	punctuation := make(map[string]string, len(keys))
+	for i, key := range keys {
+		punctuation[key] = targets[i]
+	}
+
+	rs.PunctuationMap = punctuation
+	shuffleWheels(rng, rs.OneRuneAccentsWheel, 1)
+	shuffleWheels(rng, rs.TwoRunesAccentsWheel, 0)
+	shuffleWheels(rng, rs.ConsonantWheel, 1)
+
+	for _, script := range sourceScripts[1:] {
+		conjunctionMap, letterMap := script.maps(rs)
+		if *conjunctionMap, *letterMap, err = generateScriptMaps(rng, script, *conjunctionMap, *letterMap); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// generatedRulesetCorpus is round-tripped by every generated dialect, besides each of its
+// conjunctions followed by every letter of its script
+var generatedRulesetCorpus = []string{
+	"Hello, World! How are you?",
+	"The QUICK brown fox jumps over the lazy dog.\nshell chat, holau fran",
+	"café naïve résumé Ångström",
+	"Привет, мир! Сәлем Γεια σου κόσμε",
+	"123 -3.14 'quoted' it's",
+}
+
+// generatedRulesetRoundTrips reports whether a generated dialect is valid and reads its corpus back
+func generatedRulesetRoundTrips(rs *Ruleset) bool {
+	if rs.ValidateStrict() != nil {
+		return false
+	}
+	tr := New(Options{Ruleset: rs, DisableTimestamp: true})
+	roundTrips := func(input string) bool { return tr.FromPejelagarto(tr.ToPejelagarto(input)) == input }
+	for _, input := range generatedRulesetCorpus {
+		if !roundTrips(input) {
+			return false
+		}
+	}
+	// A conjunction value must not run into the letters written after it
+	for _, script := range sourceScripts {
+		conjunctionMap, _ := script.maps(rs)
+		for _, key := range sortedKeys(*conjunctionMap) {
+			words := make([]string, 0, len(script.alphabet))
+			for _, r := range script.alphabet {
+				words = append(words, key+string(r))
+			}
+			if !roundTrips(strings.Join(words, " ")) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// generateScriptMaps draws new letter pairs and conjunction values for the keys of a script's maps
+// It fails when maxConjunctionDraws letters do not give every conjunction a value
+func generateScriptMaps(rng *rand.Rand, script sourceScript, conjunctionMap, letterMap map[string]string) (map[string]string, map[string]string, error) {
+	// Letters: pair up shuffled vowels and shuffled consonants, an odd one out maps to itself
+	var vowels, consonants []string
+	for _, letter := range sortedKeys(letterMap) {
+		if script.isVowel(letter) {
+			vowels = append(vowels, letter)
+		} else {
+			consonants = append(consonants, letter)
+		}
+	}
+	letters := make(map[string]string, len(letterMap))
+	for _, group := range [][]string{vowels, consonants} {
+		rng.Shuffle(len(group), func(i, j int) { group[i], group[j] = group[j], group[i] })
+		for i := 0; i+1 < len(group); i += 2 {
+			letters[group[i]] = group[i+1]
+			letters[group[i+1]] = group[i]
+		}
+		if len(group)%2 == 1 {
+			last := group[len(group)-1]
+			letters[last] = last
+		}
+	}
+
+	// Conjunctions: new values from the letters the letter map leaves alone
+	var alphabet []rune
+	for _, r := range script.alphabet {
+		if _, mapped := letters[string(r)]; !mapped {
+			alphabet = append(alphabet, r)
+		}
+	}
+	// A value starting another would be read back as the longer one when the text completes it
+	var values []string
+	startsAnother := func(value string) bool {
+		for _, other := range values {
+			if strings.HasPrefix(other, value) || strings.HasPrefix(value, other) {
+				return true
+			}
+		}
+		return false
+	}
+	draws := 0
+	draw := func() (rune, error) {
+		if draws == maxConjunctionDraws || len(alphabet) == 0 {
+			return 0, fmt.Errorf("%s conjunctions: no values left in %d letters drawn from %q", script.name, draws, string(alphabet))
+		}
+		draws++
+		return alphabet[rng.Intn(len(alphabet))], nil
+	}
+	conjunctions := make(map[string]string, len(conjunctionMap))
+	for _, key := range sortedKeys(conjunctionMap) {
+		length := utf8.RuneCountInString(key)
+		for {
+			value := make([]rune, length)
+			for i := range value {
+				var err error
+				if value[i], err = draw(); err != nil {
+					return nil, nil, err
+				}
+				for i > 0 && value[i] == value[i-1] {
+					if value[i], err = draw(); err != nil {
+						return nil, nil, err
+					}
+				}
+			}
+			if !startsAnother(string(value)) {
+				values = append(values, string(value))
+				conjunctions[key] = string(value)
+				break
+			}
+		}
+	}
+	return conjunctions, letters, nil
+}
+
+// shuffleWheels reorders the forms of every wheel in place, leaving the first fixed forms alone
+func shuffleWheels(rng *rand.Rand, wheels map[rune][]string, fixed int) {
+	bases := make([]rune, 0, len(wheels))
+	for base := range wheels {
+		bases = append(bases, base)
+	}
+	sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+
+	for _, base := range bases {
+		forms := wheels[base]
+		if len(forms) <= fixed {
+			continue
+		}
+		movable := forms[fixed:]
+		rng.Shuffle(len(movable), func(i, j int) { movable[i], movable[j] = movable[j], movable[i] })
+	}
+}
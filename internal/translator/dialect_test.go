@@ -0,0 +1,96 @@
+package translator
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzGeneratedDialectRoundTrip tests that any passphrase gives a valid dialect that round-trips
+func FuzzGeneratedDialectRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("", "Hello, World! 123")
+	f.Add("correct horse battery staple", "the quick brown fox jumps over the lazy dog? ¡Hola! shell chat")
+	f.Fuzz(func(t *testing.T, passphrase string, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		rs := generatedRuleset(t, passphrase)
+		if err := rs.Validate(); err != nil {
+			t.Fatalf("GenerateRuleset(%q) is invalid: %v", passphrase, err)
+		}
+
+		checkRoundTrip(t, New(Options{Ruleset: rs, DisableTimestamp: true}), input)
+	})
+}
+
+// TestGenerateRuleset verifies dialects are reproducible, differ between passphrases and keep the constraints
+func TestGenerateRuleset(t *testing.T) {
+	first := generatedRuleset(t, "our little secret")
+	if again := generatedRuleset(t, "our little secret"); !reflect.DeepEqual(first, again) {
+		t.Fatalf("the same passphrase generated different dialects")
+	}
+	if other := generatedRuleset(t, "another secret"); reflect.DeepEqual(first.LetterMap, other.LetterMap) {
+		t.Errorf("different passphrases generated the same letter map")
+	}
+
+	for key, value := range first.LetterMap {
+		_, keyIsVowel := OneRuneAccentsWheel[[]rune(key)[0]]
+		_, valueIsVowel := OneRuneAccentsWheel[[]rune(value)[0]]
+		if keyIsVowel != valueIsVowel {
+			t.Errorf("letter %q maps to %q, mixing vowels and consonants", key, value)
+		}
+	}
+	for key, value := range first.ConjunctionMap {
+		if strings.ContainsAny(value, strings.Join(sortedKeys(first.LetterMap), "")) {
+			t.Errorf("conjunction %q maps to %q, which uses letters of the letter map", key, value)
+		}
+	}
+	for base, forms := range first.OneRuneAccentsWheel {
+		if forms[0] != string(base) {
+			t.Errorf("wheel of %q starts with %q", base, forms[0])
+		}
+	}
+
+	// A conjunction followed by a letter must not be read as another conjunction ("elh" was read as "leg")
+	for i := 0; i < 20; i++ {
+		tr := New(Options{Ruleset: generatedRuleset(t, fmt.Sprintf("team-%d", i)), DisableTimestamp: true})
+		for _, word := range []string{"elh", "chz", "tht", "holau"} {
+			if got := tr.FromPejelagarto(tr.ToPejelagarto(word)); got != word {
+				t.Errorf("team-%d: %q reads back as %q", i, word, got)
+			}
+		}
+	}
+
+	input := "The quick brown fox jumps over the lazy dog."
+	private := TranslateToPejelagartoWithKey(input, "our little secret")
+	if got := TranslateFromPejelagartoWithKey(private, "our little secret"); !strings.HasPrefix(got, input+"\n") {
+		t.Errorf("TranslateFromPejelagartoWithKey() = %q, want %q and a timestamp line", got, input)
+	}
+	if got := TranslateFromPejelagarto(private); strings.HasPrefix(got, input) {
+		t.Errorf("the default dialect read the private dialect: %q", got)
+	}
+}
+
+// TestGenerateScriptMapsGivesUp verifies a script without enough free letters fails instead of drawing forever
+func TestGenerateScriptMapsGivesUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		alphabet string
+	}{
+		{"no free letter", "ab"},
+		{"one free letter for a longer value", "abc"},
+	}
+	for _, tt := range tests {
+		script := sourceScript{name: "test", alphabet: tt.alphabet, vowels: "a"}
+		letters := map[string]string{"a": "a", "b": "b"}
+		_, _, err := generateScriptMaps(rand.New(rand.NewSource(1)), script, map[string]string{"the": "ele"}, letters)
+		if err == nil {
+			t.Errorf("%s: generateScriptMaps succeeded", tt.name)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package translator
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Compiled replacement engine
+// A bijective map is compiled once into a trie of lowercased keys. A translation scans the text
+// a single time, walking the trie from every position to collect all candidate matches, and then
+// resolves overlapping candidates in rule order (index group, then longest key, then alphabetical).
+// The result is identical to trying every key in turn over the whole text, which is what the
+// pipeline did before, without rebuilding the text, escape and marker maps once per key
+
+// quotedWordScanLimit bounds how far back a match looks for the quote that opens its word
+const quotedWordScanLimit = 50
+
+// Markers that the replacement engine escapes in its input, kept so that text containing them
+// translates exactly as it did when they were used to delimit replaced spans
+const (
+	startMarkerRune = '\uFFF0'
+	endMarkerRune   = '\uFFF1'
+)
+
+// replacementRule is one key -> value entry of a bijective map
+type replacementRule struct {
+	runeLen int    // length of the key in runes
+	value   string // replacement, without its quote prefix when the key is a quoted Pejelagarto pattern
+	quoted  bool   // the key starts with a quote, so quoted-word protection does not apply
+}
+
+// trieNode is a node of the trie of lowercased keys
+type trieNode struct {
+	children map[rune]*trieNode
+	rules    []int // rules whose key ends at this node, in rule order
+}
+
+// replacementEngine applies a compiled bijective map
+// It is immutable once built and safe for concurrent use
+type replacementEngine struct {
+	rules []replacementRule
+	root  *trieNode
+}
+
+// compileReplacements builds an engine applying bijectiveMap in the order given by indices
+// Keys are tried by index group, then by byte length descending, then alphabetically
+func compileReplacements(bijectiveMap map[int32]map[string]string, indices []int32) *replacementEngine {
+	engine := &replacementEngine{root: &trieNode{}}
+
+	for _, index := range indices {
+		replacements := bijectiveMap[index]
+
+		keys := make([]string, 0, len(replacements))
+		for key := range replacements {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) > len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+
+		for _, key := range keys {
+			keyRunes := []rune(key)
+			if !matchableKey(keyRunes) {
+				continue
+			}
+
+			// For FromPejelagarto: if the key has a quote prefix (Pejelagarto multi-rune pattern),
+			// remove it from the output value so it doesn't appear in Human text
+			rule := replacementRule{runeLen: len(keyRunes), value: replacements[key]}
+			if keyRunes[0] == '\'' {
+				rule.quoted = true
+				rule.value = strings.TrimPrefix(rule.value, "'")
+			}
+
+			node := engine.root
+			for _, r := range keyRunes {
+				r = unicode.ToLower(r)
+				if node.children == nil {
+					node.children = make(map[rune]*trieNode)
+				}
+				child, exists := node.children[r]
+				if !exists {
+					child = &trieNode{}
+					node.children[r] = child
+				}
+				node = child
+			}
+			node.rules = append(node.rules, len(engine.rules))
+			engine.rules = append(engine.rules, rule)
+		}
+	}
+
+	return engine
+}
+
+// matchableKey reports whether a key can ever match
+// Empty keys, keys with a quote after their first rune (quotes are word boundaries) and keys
+// with a letter whose case conversion is not reversible are never applied
+func matchableKey(keyRunes []rune) bool {
+	if len(keyRunes) == 0 {
+		return false
+	}
+	for i, r := range keyRunes {
+		if r == '\'' && i > 0 {
+			return false
+		}
+		if !reversibleCase(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// reversibleCase reports whether upper -> lower -> upper gives back the same letter
+func reversibleCase(r rune) bool {
+	return !unicode.IsLetter(r) || unicode.ToUpper(unicode.ToLower(r)) == unicode.ToUpper(r)
+}
+
+// apply replaces every match in input
+// Replaced text is never matched again, and a key does not match:
+//   - at an escaped position (after InternalEscapeChar or OutputEscapeChar)
+//   - inside a word that starts with an unprocessed quote, unless the key itself starts with a quote
+//   - where a letter's case conversion is not reversible
+func (e *replacementEngine) apply(input string) string {
+	result, _ := e.run(input, false)
+	return result
+}
+
+// applyAligned replaces every match in input like apply, also returning which runes each match replaced
+func (e *replacementEngine) applyAligned(input string) (string, alignment) {
+	return e.run(input, true)
+}
+
+// run replaces every match in input, recording the alignment only when aligned is set
+func (e *replacementEngine) run(input string, aligned bool) (string, alignment) {
+	// Escape the markers and the escape character so positions match the previous implementation
+	text := []rune(internalEscape(input, string([]rune{startMarkerRune, endMarkerRune})))
+	n := len(text)
+
+	// Find every candidate match in a single scan
+	occurrences := make([][]int, len(e.rules))
+	hasQuote := false
+	for p := 0; p < n; p++ {
+		if text[p] == '\'' {
+			hasQuote = true
+		}
+		if isEscapedAt(text, p) {
+			continue
+		}
+		node := e.root
+		for q := p; q < n && reversibleCase(text[q]); q++ {
+			node = node.children[unicode.ToLower(text[q])]
+			if node == nil {
+				break
+			}
+			for _, rule := range node.rules {
+				occurrences[rule] = append(occurrences[rule], p)
+			}
+		}
+	}
+
+	// Resolve candidates in rule order; claims[i] is 1 + the rule that replaced position i
+	claims := make([]int32, n)
+	starts := make([]bool, n)
+	for ruleIdx, positions := range occurrences {
+		rule := e.rules[ruleIdx]
+		owner := int32(ruleIdx + 1)
+		next := 0
+		for _, p := range positions {
+			if p < next || claimedWithin(claims, p, p+rule.runeLen) {
+				continue
+			}
+			if !rule.quoted && hasQuote && inQuotedWord(text, claims, p, owner) {
+				continue
+			}
+			for i := p; i < p+rule.runeLen; i++ {
+				claims[i] = owner
+			}
+			starts[p] = true
+			next = p + rule.runeLen
+		}
+	}
+
+	var result strings.Builder
+	result.Grow(len(input) + len(input)/2)
+	var cuts alignment
+	source, target := 0, 0 // rune positions in input and in the unescaped output
+	if aligned {
+		cuts = alignment{{0, 0}}
+	}
+	for i := 0; i < n; {
+		if !starts[i] {
+			width := 1
+			if text[i] == InternalEscapeChar && i+1 < n {
+				width = 2 // an escape added above and the rune it escapes, never matched
+			}
+			result.WriteString(string(text[i : i+width]))
+			if aligned {
+				source, target = source+1, target+1
+				cuts = append(cuts, alignCut{source, target})
+			}
+			i += width
+			continue
+		}
+		rule := e.rules[claims[i]-1]
+		replacement := matchCase(string(text[i:i+rule.runeLen]), rule.value)
+		result.WriteString(replacement)
+		if aligned {
+			source, target = source+rule.runeLen, target+utf8.RuneCountInString(replacement)
+			cuts = append(cuts, alignCut{source, target})
+		}
+		i += rule.runeLen
+	}
+
+	// Restore escaped characters (this restores original markers that were in the input)
+	return internalUnescape(result.String()), cuts
+}
+
+// isEscapedAt reports whether position p is an escape character or follows one
+func isEscapedAt(text []rune, p int) bool {
+	isEscape := func(r rune) bool { return r == InternalEscapeChar || r == OutputEscapeChar }
+	return (p+1 < len(text) && isEscape(text[p])) || (p > 0 && isEscape(text[p-1]))
+}
+
+// claimedWithin reports whether any position in [start, end) was already replaced
+func claimedWithin(claims []int32, start, end int) bool {
+	for i := start; i < end; i++ {
+		if claims[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// inQuotedWord reports whether position p is inside a word opened by an unprocessed quote
+// A quote at the start of a word protects the entire word from being re-matched
+// Text replaced by earlier rules ends a word; text replaced by the current rule does not,
+// because all candidates of one rule are checked against the text as it was before that rule
+func inQuotedWord(text []rune, claims []int32, p int, owner int32) bool {
+	wordStart := p
+	for i := p - 1; i >= 0 && i >= p-quotedWordScanLimit; i-- {
+		endsWord := claims[i] != 0 && claims[i] != owner
+		if endsWord || (!unicode.IsLetter(text[i]) && text[i] != '\'') {
+			wordStart = i + 1
+			break
+		}
+		if i == 0 {
+			wordStart = 0
+		}
+	}
+
+	for i := p - 1; i >= wordStart; i-- {
+		if text[i] == '\'' {
+			return true
+		}
+	}
+	return false
+}
+
+// compiledRules holds the replacement engines and the accent and consonant wheels of a dialect
+type compiledRules struct {
+	mapsToPejelagarto          *replacementEngine
+	mapsFromPejelagarto        *replacementEngine
+	punctuationToPejelagarto   *replacementEngine
+	punctuationFromPejelagarto *replacementEngine
+	punctuation                map[string]string
+	wheels                     accentWheels
+	consonants                 consonantWheels
+	fingerprint                string // of the ruleset, the glossary left out
+}
+
+// compileRuleset validates a dialect and builds its engines; rs must not be modified afterwards
+// Escape characters are not part of the compiled rules, the current ones are always used
+func compileRuleset(rs *Ruleset) (*compiledRules, error) {
+	return compileRulesetWithGlossary(rs, nil)
+}
+
+// compileRulesetWithGlossary validates a dialect and builds its engines extended with the pairs of a glossary
+func compileRulesetWithGlossary(rs *Ruleset, glossary map[string]string) (*compiledRules, error) {
+	if err := rs.Validate(); err != nil {
+		return nil, err
+	}
+	return compileRules(rs, glossary), nil
+}
+
+// compileRules builds the engines of a dialect without validating it, for the archived versions
+// that were valid when released
+func compileRules(rs *Ruleset, glossary map[string]string) *compiledRules {
+	bijectiveMap := createBijectiveMapFrom(rs.replacementMaps()...)
+	for index, replacements := range createGlossaryMap(glossary) {
+		bijectiveMap[index] = replacements
+	}
+	punctuationMap := createBijectiveMapFrom(rs.PunctuationMap)
+	return &compiledRules{
+		mapsToPejelagarto:          compileReplacements(bijectiveMap, getSortedIndices(bijectiveMap, true)),
+		mapsFromPejelagarto:        compileReplacements(bijectiveMap, getSortedIndices(bijectiveMap, false)),
+		punctuationToPejelagarto:   compileReplacements(punctuationMap, getSortedPunctuationIndices(punctuationMap, true)),
+		punctuationFromPejelagarto: compileReplacements(punctuationMap, getSortedPunctuationIndices(punctuationMap, false)),
+		punctuation:                rs.PunctuationMap,
+		wheels:                     newAccentWheels(rs.OneRuneAccentsWheel, rs.TwoRunesAccentsWheel),
+		consonants:                 newConsonantWheels(rs.ConsonantWheel),
+		fingerprint:                rs.Fingerprint(),
+	}
+}
+
+// compiledRulesCache is built on first use and cleared by UseRuleset
+var compiledRulesCache atomic.Pointer[compiledRules]
+
+// currentCompiledRules returns the engines for the current maps, compiling them if needed
+// The maps are exported variables that can be edited directly, so they are validated first and a
+// *RulesetError is returned, and not cached, when they are not a valid dialect
+func currentCompiledRules() (*compiledRules, error) {
+	if rules := compiledRulesCache.Load(); rules != nil {
+		return rules, nil
+	}
+
+	rules, err := compileRuleset(CurrentRuleset())
+	if err != nil {
+		return nil, err
+	}
+	// Concurrent first calls may both compile; they build identical engines, so keep whichever lands first
+	if compiledRulesCache.CompareAndSwap(nil, rules) {
+		return rules, nil
+	}
+	return compiledRulesCache.Load(), nil
+}
+
+// withCurrentRules applies a stage of the current dialect, leaving the text unchanged when the
+// maps are not a valid dialect (see currentCompiledRules)
+func withCurrentRules(input string, stage func(*compiledRules, string) string) string {
+	rules, err := currentCompiledRules()
+	if err != nil {
+		return input
+	}
+	return stage(rules, input)
+}
+
+// resetCompiledRules discards the compiled engines after the translation maps change
+func resetCompiledRules() {
+	compiledRulesCache.Store(nil)
+}
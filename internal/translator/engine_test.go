@@ -0,0 +1,304 @@
+package translator
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FuzzCompiledReplacementsMatchReference tests that the compiled engines produce exactly the
+// output of the original key-by-key replacement loop, in both directions
+func FuzzCompiledReplacementsMatchReference(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("")
+	f.Add("Hello the fran, hola el leg!")
+	f.Add("eleg 'ady 'araka l'arak \\\uFFF0 \u00AD'x")
+	f.Add("It's a Chef's THE ShEll \"quoted\" (a.b.c) ... -1")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		bijectiveMap := createBijectiveMapFrom(CurrentRuleset().replacementMaps()...)
+		punctuationMap := createPunctuationBijectiveMap()
+		compiled := currentRules(t)
+		cases := []struct {
+			name      string
+			engine    *replacementEngine
+			reference func(string) string
+		}{
+			{"maps to", compiled.mapsToPejelagarto, func(s string) string {
+				return referenceApplyReplacements(s, bijectiveMap, getSortedIndices(bijectiveMap, true))
+			}},
+			{"maps from", compiled.mapsFromPejelagarto, func(s string) string {
+				return referenceApplyReplacements(s, bijectiveMap, getSortedIndices(bijectiveMap, false))
+			}},
+			{"punctuation to", compiled.punctuationToPejelagarto, func(s string) string {
+				return referenceApplyReplacements(s, punctuationMap, getSortedPunctuationIndices(punctuationMap, true))
+			}},
+			{"punctuation from", compiled.punctuationFromPejelagarto, func(s string) string {
+				return referenceApplyReplacements(s, punctuationMap, getSortedPunctuationIndices(punctuationMap, false))
+			}},
+		}
+		for _, c := range cases {
+			if got, want := c.engine.apply(input), c.reference(input); got != want {
+				t.Errorf("%s: compiled engine differs from reference\nInput:     %q\nCompiled:  %q\nReference: %q", c.name, input, got, want)
+			}
+		}
+	})
+}
+
+// currentRules returns the compiled current dialect, failing the test when it is invalid
+func currentRules(tb testing.TB) *compiledRules {
+	tb.Helper()
+	rules, err := currentCompiledRules()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return rules
+}
+
+// BenchmarkMapReplacements measures the compiled map replacement stage on a paragraph of text
+func BenchmarkMapReplacements(b *testing.B) {
+	input := strings.Repeat("Hello there, the fran said: 'hola' to the chef with a leg of lamb. ", 20)
+	for i := 0; i < b.N; i++ {
+		applyMapReplacementsToPejelagarto(input)
+	}
+}
+
+// referenceApplyReplacements is the original key-by-key implementation the compiled engine replaced
+// It applies replacements from the bijective map in the specified order
+func referenceApplyReplacements(input string, bijectiveMap map[int32]map[string]string, indices []int32) string {
+	// Use special Unicode characters as markers that won't be in normal text
+	const startMarker = "\uFFF0"
+	const endMarker = "\uFFF1"
+
+	// Escape any markers that appear in the input to preserve them
+	// Convert markers to runes for proper escaping
+	startMarkerRune := []rune(startMarker)[0]
+	endMarkerRune := []rune(endMarker)[0]
+	result := internalEscape(input, string([]rune{startMarkerRune, endMarkerRune}))
+
+	for _, index := range indices {
+		replacements := bijectiveMap[index]
+
+		// Sort keys by length descending, then alphabetically
+		keys := make([]string, 0, len(replacements))
+		for key := range replacements {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) > len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+
+		for _, key := range keys {
+			value := replacements[key]
+
+			// For FromPejelagarto: if the key has a quote prefix (Pejelagarto multi-rune pattern),
+			// remove it from the output value so it doesn't appear in Human text
+			outputValue := value
+			if strings.HasPrefix(key, "'") {
+				// This is a Pejelagarto pattern being converted back to Human
+				// Remove any quote prefix from the output
+				outputValue = strings.TrimPrefix(value, "'")
+			}
+
+			// Find and replace all occurrences (case-insensitive)
+			newResult := strings.Builder{}
+			pos := 0
+			resultRunes := []rune(result)
+			keyRunes := []rune(key)
+
+			// Pre-calculate marker positions and escape positions for O(n) performance
+			markerMap := make(map[int]int)   // pos -> marker depth at that position
+			escapedMap := make(map[int]bool) // pos -> is this position escaped
+			depth := 0
+			startMarkerRune := []rune(startMarker)[0]
+			endMarkerRune := []rune(endMarker)[0]
+
+			// First pass: identify escaped characters
+			// We need to check for BOTH backslash escapes (internal) and soft hyphen escapes (output)
+			for i := 0; i < len(resultRunes); i++ {
+				if (resultRunes[i] == InternalEscapeChar || resultRunes[i] == OutputEscapeChar) && i+1 < len(resultRunes) {
+					escapedMap[i+1] = true
+					escapedMap[i] = true // Mark the escape character itself as escaped too
+				}
+			}
+
+			// Second pass: calculate marker depth, skipping escaped characters
+			for i := 0; i < len(resultRunes); i++ {
+				markerMap[i] = depth
+
+				// Only count as marker if not escaped
+				if !escapedMap[i] {
+					if resultRunes[i] == startMarkerRune {
+						depth++
+					} else if resultRunes[i] == endMarkerRune {
+						depth--
+					}
+				}
+			}
+
+			for pos < len(resultRunes) {
+				// Check if current character is escaped
+				if escapedMap[pos] {
+					// This character is escaped, just copy it
+					newResult.WriteRune(resultRunes[pos])
+					pos++
+					continue
+				}
+
+				// Check if we're inside markers using pre-calculated map
+				if markerMap[pos] > 0 {
+					// Skip characters inside markers
+					newResult.WriteRune(resultRunes[pos])
+					pos++
+					continue
+				}
+
+				// Check if we're inside a quoted multi-rune pattern (for non-quoted keys only)
+				// A quote at the start of a word protects the entire word from being re-matched
+				// BUT only if the quote hasn't been processed yet (i.e., not inside markers)
+				if len(keyRunes) > 0 && keyRunes[0] != '\'' {
+					inQuotedWord := false
+					// Look backwards in the current word for an unprocessed quote
+					// Optimized: limit backward scan to word boundary
+					wordStart := pos
+					for i := pos - 1; i >= 0 && i >= pos-50; i-- { // limit backward scan
+						if !unicode.IsLetter(resultRunes[i]) && resultRunes[i] != '\'' {
+							wordStart = i + 1
+							break
+						}
+						if i == 0 {
+							wordStart = 0
+						}
+					}
+
+					for i := pos - 1; i >= wordStart; i-- {
+						if resultRunes[i] == '\'' {
+							// Check if this quote is inside markers using pre-calculated map
+							if markerMap[i] == 0 {
+								inQuotedWord = true
+							}
+							break
+						}
+						if !unicode.IsLetter(resultRunes[i]) {
+							break
+						}
+					}
+					if inQuotedWord {
+						// Skip this character - it's part of a quoted pattern
+						newResult.WriteRune(resultRunes[pos])
+						pos++
+						continue
+					}
+				}
+
+				// Check if current position matches the key (case-insensitive)
+				if pos+len(keyRunes) <= len(resultRunes) {
+					matched := true
+					matchEndPos := pos + len(keyRunes)
+
+					// Check if the match would span across a quote character (but not start with one)
+					// Quotes act as boundaries - patterns can't span across them
+					if len(keyRunes) > 0 && keyRunes[0] != '\'' {
+						for i := pos; i < matchEndPos; i++ {
+							if resultRunes[i] == '\'' {
+								matched = false
+								break
+							}
+						}
+					}
+
+					// Check if any part of the potential match is inside markers
+					// Use pre-calculated marker map
+					if matched {
+						for i := pos; i < matchEndPos; i++ {
+							if markerMap[i] > 0 {
+								matched = false
+								break
+							}
+						}
+					}
+
+					// Also check for case-insensitive character match
+					if matched {
+						for i := 0; i < len(keyRunes); i++ {
+							resultChar := resultRunes[pos+i]
+							keyChar := keyRunes[i]
+
+							// Check if characters match (case-insensitive)
+							if unicode.ToLower(resultChar) != unicode.ToLower(keyChar) {
+								matched = false
+								break
+							}
+
+							// Additional check: ensure case conversion is reversible for this character
+							// Skip match if either character has non-reversible case conversion
+							if unicode.IsLetter(resultChar) {
+								// Check if upper->lower->upper is reversible
+								if unicode.ToUpper(unicode.ToLower(resultChar)) != unicode.ToUpper(resultChar) {
+									matched = false
+									break
+								}
+							}
+							if unicode.IsLetter(keyChar) {
+								// Check if upper->lower->upper is reversible
+								if unicode.ToUpper(unicode.ToLower(keyChar)) != unicode.ToUpper(keyChar) {
+									matched = false
+									break
+								}
+							}
+						}
+					}
+
+					if matched {
+						// Extract matched text with original casing
+						matchedText := string(resultRunes[pos : pos+len(keyRunes)])
+						// Apply case matching
+						casedValue := matchCase(matchedText, outputValue)
+						// Wrap in markers and add
+						newResult.WriteString(startMarker)
+						newResult.WriteString(casedValue)
+						newResult.WriteString(endMarker)
+						pos += len(keyRunes)
+						continue
+					}
+				}
+
+				// No match, just copy the character
+				newResult.WriteRune(resultRunes[pos])
+				pos++
+			}
+
+			result = newResult.String()
+		}
+	}
+
+	// Remove all working markers, but NOT escaped ones
+	// We need to manually iterate to skip escaped markers
+	var cleanResult strings.Builder
+	resultRunes := []rune(result)
+	for i := 0; i < len(resultRunes); i++ {
+		// Check if this is an escaped character (preceded by backslash)
+		isEscaped := i > 0 && resultRunes[i-1] == InternalEscapeChar
+
+		// Skip unescaped markers
+		if !isEscaped && (resultRunes[i] == startMarkerRune || resultRunes[i] == endMarkerRune) {
+			continue
+		}
+
+		cleanResult.WriteRune(resultRunes[i])
+	}
+	result = cleanResult.String()
+
+	// THEN restore escaped characters (this restores original markers that were in the input)
+	result = internalUnescape(result)
+
+	return result
+}
@@ -0,0 +1,50 @@
+package translator
+
+import (
+	"testing"
+	"time"
+)
+
+// fixedTestTime is the time hidden by the translators of fixedTestTranslator
+var fixedTestTime = time.Date(2026, time.March, 14, 15, 9, 26, 0, time.UTC)
+
+// fixedTestTranslator returns a translator with reproducible output: unless opts sets them, it hides
+// fixedTestTime and places the timestamp characters with SeededRand(1)
+func fixedTestTranslator(opts Options) *Translator {
+	if opts.Clock == nil && opts.Timestamp.IsZero() {
+		opts.Timestamp = fixedTestTime
+	}
+	if opts.Rand == nil {
+		opts.Rand = SeededRand(1)
+	}
+	return New(opts)
+}
+
+// checkRoundTrip fails the test unless tr reads back the input it translated, less the timestamp it
+// hides, and returns the Pejelagarto text
+func checkRoundTrip(tb testing.TB, tr *Translator, input string) string {
+	tb.Helper()
+	pejelagarto := tr.ToPejelagarto(input)
+	want, reversed := input, tr.FromPejelagarto(pejelagarto)
+	if !tr.opts.DisableTimestamp {
+		if !tr.opts.Lossless {
+			want = RemoveTimestampSpecialCharacters(want)
+		}
+		want, _ = removeISO8601timestamp(want)
+		reversed, _ = removeISO8601timestamp(reversed)
+	}
+	if reversed != want {
+		tb.Errorf("round-trip failed\nInput:       %q\nPejelagarto: %q\nReversed:    %q", want, pejelagarto, reversed)
+	}
+	return pejelagarto
+}
+
+// generatedRuleset returns the dialect of passphrase, failing the test when none can be generated
+func generatedRuleset(tb testing.TB, passphrase string) *Ruleset {
+	tb.Helper()
+	rs, err := GenerateRuleset(passphrase)
+	if err != nil {
+		tb.Fatalf("GenerateRuleset(%q): %v", passphrase, err)
+	}
+	return rs
+}
@@ -0,0 +1,193 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Glossaries
+// A glossary holds whole-word pairs such as the built-in "hello" -> "araka", registered on top of
+// a dialect without rebuilding it. Every pair is checked against the rules of the conjunction map
+// before it is accepted: equal rune lengths, a replacement written only with the letters the letter
+// map leaves alone and without repeated neighbours, and no collision with the dialect's conjunctions
+// Accepted pairs are tried before every entry of the maps (see getSortedIndices)
+
+// glossaryIndexBase shifts the indices of glossary pairs in a bijective map above every rune length
+const glossaryIndexBase int32 = 1 << 16
+
+// GlossaryError explains why a pair was rejected
+type GlossaryError struct {
+	Word        string `json:"word"`
+	Replacement string `json:"replacement"`
+	Reason      string `json:"reason"`
+}
+
+func (e *GlossaryError) Error() string {
+	return fmt.Sprintf("glossary pair %q -> %q rejected: %s", e.Word, e.Replacement, e.Reason)
+}
+
+// Glossary holds the word pairs accepted for a dialect
+// It is safe for concurrent use
+type Glossary struct {
+	mu      sync.RWMutex
+	ruleset *Ruleset
+	entries map[string]string
+}
+
+// NewGlossary returns an empty glossary checked against rs, nil meaning the current dialect
+func NewGlossary(rs *Ruleset) *Glossary {
+	if rs == nil {
+		rs = CurrentRuleset()
+	}
+	return &Glossary{ruleset: rs.Clone(), entries: make(map[string]string)}
+}
+
+// Add checks a pair and adds it, replacing the pair of the same word if there is one
+// Both sides are stored lowercased, like the maps; an unsafe pair returns a *GlossaryError
+func (g *Glossary) Add(word, replacement string) error {
+	word, replacement = strings.ToLower(word), strings.ToLower(replacement)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if reason := g.check(word, replacement); reason != "" {
+		return &GlossaryError{Word: word, Replacement: replacement, Reason: reason}
+	}
+	g.entries[word] = replacement
+	return nil
+}
+
+// Remove deletes the pair of a word, if any
+func (g *Glossary) Remove(word string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.entries, strings.ToLower(word))
+}
+
+// Entries returns a copy of the accepted pairs
+func (g *Glossary) Entries() map[string]string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return copyStringMap(g.entries)
+}
+
+// check returns why a lowercased pair is unsafe, or "" if it can be added
+func (g *Glossary) check(word, replacement string) string {
+	wordRunes := []rune(word)
+	if len(wordRunes) < 2 {
+		return "words must have at least 2 letters, single letters belong to the letter map"
+	}
+	if utf8.RuneCountInString(replacement) != len(wordRunes) {
+		return fmt.Sprintf("word (len=%d) and replacement (len=%d) must have equal rune lengths",
+			len(wordRunes), utf8.RuneCountInString(replacement))
+	}
+
+	// The word is made of letters of a single script, all with reversible case
+	var script *sourceScript
+	for i := range sourceScripts {
+		if unicode.Is(sourceScripts[i].table, wordRunes[0]) {
+			script = &sourceScripts[i]
+		}
+	}
+	if script == nil {
+		return fmt.Sprintf("word must be written in one of the %s scripts", scriptNames())
+	}
+	for _, r := range wordRunes {
+		if !unicode.IsLetter(r) || !unicode.Is(script.table, r) {
+			return fmt.Sprintf("word must only contain %s letters, found %q", script.name, r)
+		}
+		if !reversibleCase(r) {
+			return fmt.Sprintf("letter %q of the word has non-reversible case conversion", r)
+		}
+	}
+
+	// The replacement only uses the letters the letter map leaves alone, so that no other entry
+	// ever produces it, and never repeats a letter twice in a row
+	conjunctionMap, letterMap := script.maps(g.ruleset)
+	var previous rune
+	for _, r := range replacement {
+		if _, mapped := (*letterMap)[string(r)]; mapped || !strings.ContainsRune(script.alphabet, r) {
+			return fmt.Sprintf("replacement may only use the %s conjunction letters %q, found %q",
+				script.name, conjunctionLetters(*script, *letterMap), r)
+		}
+		if r == previous {
+			return fmt.Sprintf("replacement repeats %q, which could be read as two letters", r)
+		}
+		previous = r
+	}
+
+	// No collision with the conjunctions of any script or the other pairs of the glossary
+	// A replacement that starts another one would be read back as the longer one when the letters
+	// after the shorter one complete it
+	if _, exists := (*conjunctionMap)[word]; exists {
+		return fmt.Sprintf("word %q is already a conjunction of the dialect", word)
+	}
+	for _, m := range g.ruleset.replacementMaps() {
+		for key, value := range m {
+			if utf8.RuneCountInString(value) < 2 {
+				continue
+			}
+			if reason := collision(replacement, fmt.Sprintf("conjunction %q", key), strings.ToLower(value)); reason != "" {
+				return reason
+			}
+		}
+	}
+	for key, value := range g.entries {
+		if key == word {
+			continue
+		}
+		if reason := collision(replacement, fmt.Sprintf("glossary word %q", key), value); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// collision explains how a replacement collides with the value of another entry, or returns ""
+func collision(replacement, owner, value string) string {
+	switch {
+	case replacement == value:
+		return fmt.Sprintf("replacement is already used by %s", owner)
+	case strings.HasPrefix(replacement, value):
+		return fmt.Sprintf("replacement starts with %q, the replacement of %s", value, owner)
+	case strings.HasPrefix(value, replacement):
+		return fmt.Sprintf("replacement is the start of %q, the replacement of %s", value, owner)
+	}
+	return ""
+}
+
+// conjunctionLetters returns the letters of a script's alphabet its letter map leaves alone
+func conjunctionLetters(script sourceScript, letterMap map[string]string) string {
+	var letters strings.Builder
+	for _, r := range script.alphabet {
+		if _, mapped := letterMap[string(r)]; !mapped {
+			letters.WriteRune(r)
+		}
+	}
+	return letters.String()
+}
+
+// scriptNames lists the names of the source scripts, e.g. "Latin, Cyrillic or Greek"
+func scriptNames() string {
+	names := make([]string, len(sourceScripts))
+	for i, script := range sourceScripts {
+		names[i] = script.name
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// createGlossaryMap creates the bijective map of glossary pairs, with indices above every map entry
+func createGlossaryMap(entries map[string]string) map[int32]map[string]string {
+	glossaryMap := make(map[int32]map[string]string)
+	for index, replacements := range createBijectiveMapFrom(entries) {
+		if index > 0 {
+			index += glossaryIndexBase
+		} else {
+			index -= glossaryIndexBase
+		}
+		glossaryMap[index] = replacements
+	}
+	return glossaryMap
+}
@@ -0,0 +1,97 @@
+package translator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// newTestGlossary returns a glossary of the built-in dialect with a few team words
+func newTestGlossary(t testing.TB) *Glossary {
+	g := NewGlossary(DefaultRuleset())
+	for word, replacement := range map[string]string{
+		"shell":  "hcjsx",
+		"world":  "tjhsc",
+		"мир":    "хчж",
+		"κόσμος": "ψθχζξφ",
+	} {
+		if err := g.Add(word, replacement); err != nil {
+			t.Fatalf("Add(%q, %q) unexpected error: %v", word, replacement, err)
+		}
+	}
+	return g
+}
+
+// FuzzGlossaryRoundTrip tests that text translated with a glossary round-trips
+func FuzzGlossaryRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Hello shell WORLD, shellworld")
+	f.Add("мир κόσμος hcjsx 'hcjsx chhcjsx")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		checkRoundTrip(t, New(Options{Glossary: newTestGlossary(t), DisableTimestamp: true}), input)
+	})
+}
+
+// TestGlossary verifies glossary pairs take priority over the maps and unsafe pairs are explained
+func TestGlossary(t *testing.T) {
+	g := newTestGlossary(t)
+	tr := New(Options{Glossary: g, DisableTimestamp: true, DisableAccents: true, DisableConsonants: true, DisableCase: true})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// "shell" wins over the "sh" and "el" conjunctions
+		{"shell", "'hcjsx"},
+		{"Shell", "'Hcjsx"},
+		{"hello world", "'araka 'tjhsc"},
+		{"мир", "'хчж"},
+	}
+	for _, tt := range tests {
+		if got := tr.ToPejelagarto(tt.input); got != tt.want {
+			t.Errorf("ToPejelagarto(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	if got := New(Options{DisableTimestamp: true}).FromPejelagarto(tr.ToPejelagarto("shell")); got == "shell" {
+		t.Errorf("a translator without the glossary read its pairs")
+	}
+
+	rejected := []struct {
+		word, replacement string
+		reason            string
+	}{
+		{"a", "c", "at least 2 letters"},
+		{"cat", "hx", "equal rune lengths"},
+		{"cat", "hax", "conjunction letters"},
+		{"cat", "hxx", "repeats"},
+		{"ch", "hs", "already a conjunction"},
+		{"cat", "jcx", "starts with \"jc\""},
+		{"dog", "tjh", "is the start of \"tjhsc\""},
+		{"pasta", "hcjsx", "already used by glossary word \"shell\""},
+		{"мир", "xyz", "Cyrillic conjunction letters"},
+		{"a1", "hx", "only contain Latin letters"},
+	}
+	for _, tt := range rejected {
+		err := g.Add(tt.word, tt.replacement)
+		var glossaryErr *GlossaryError
+		if !errors.As(err, &glossaryErr) {
+			t.Errorf("Add(%q, %q) error = %v, want *GlossaryError", tt.word, tt.replacement, err)
+			continue
+		}
+		if !strings.Contains(glossaryErr.Reason, tt.reason) {
+			t.Errorf("Add(%q, %q) reason = %q, want it to mention %q", tt.word, tt.replacement, glossaryErr.Reason, tt.reason)
+		}
+	}
+
+	// Registering a word again replaces its pair
+	if err := g.Add("World", "TJHSZ"); err != nil {
+		t.Errorf("Add() unexpected error replacing a pair: %v", err)
+	}
+	if got := g.Entries()["world"]; got != "tjhsz" {
+		t.Errorf("Entries()[\"world\"] = %q, want %q", got, "tjhsz")
+	}
+}
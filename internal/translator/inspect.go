@@ -0,0 +1,469 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Inspection of Pejelagarto text
+// TranslateFromPejelagarto accepts any string; Inspect reports why a text may not be well-formed
+// Pejelagarto (plain Human text, damaged or hand-edited output) without translating it
+
+// Severity tells whether a Diagnostic prevents a faithful translation back to Human
+type Severity string
+
+const (
+	// SeverityWarning marks text that decodes, but does not look like translator output
+	SeverityWarning Severity = "warning"
+	// SeverityError marks text that cannot be decoded back to what was encoded
+	SeverityError Severity = "error"
+)
+
+// Diagnostic codes
+const (
+	DiagnosticTimestampMissing    = "timestamp_missing"    // no timestamp characters at all
+	DiagnosticTimestampIncomplete = "timestamp_incomplete" // day, month or year character missing
+	DiagnosticTimestampConflict   = "timestamp_conflict"   // several different characters for one component
+	DiagnosticTimestampExtension  = "timestamp_extension"  // damaged version 2 seconds, offset and year digits
+	DiagnosticAccentPosition      = "accent_position"      // vowel not where the accent wheel would have moved it
+	DiagnosticDanglingEscape      = "dangling_escape"      // soft hyphen escape that escapes nothing
+	DiagnosticUTF8Sentinel        = "utf8_sentinel"        // invalid UTF-8 sentinel pair that does not round-trip
+	DiagnosticRulesetVersion      = "ruleset_version"      // written with a dialect version that is not known
+	DiagnosticMetadata            = "metadata"             // damaged metadata characters
+	DiagnosticStages              = "stages"               // written with other custom stages than the translator's
+	DiagnosticRuleset             = "ruleset"              // the translator's dialect is invalid, nothing decodes
+)
+
+// Diagnostic is a single finding about a Pejelagarto text
+type Diagnostic struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Position int      `json:"position"` // rune offset in the inspected text, -1 when it concerns the whole text
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.Position < 0 {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s at rune %d: %s", d.Severity, d.Position, d.Message)
+}
+
+// ValidationError is returned by Validate when a text has error diagnostics
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Diagnostics)+1)
+	lines = append(lines, fmt.Sprintf("text is not valid Pejelagarto (%d errors):", len(e.Diagnostics)))
+	for _, diagnostic := range e.Diagnostics {
+		lines = append(lines, "  "+diagnostic.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Inspect reports every problem found in text meant for TranslateFromPejelagarto
+func Inspect(input string) []Diagnostic {
+	return defaultTranslator.Inspect(input)
+}
+
+// Validate returns a *ValidationError if text cannot be translated back faithfully, or nil
+// Warnings are not errors; use Inspect to see them
+func Validate(input string) error {
+	return defaultTranslator.Validate(input)
+}
+
+// Validate returns a *ValidationError if text cannot be translated back faithfully with the translator's options
+func (t *Translator) Validate(input string) error {
+	var errs []Diagnostic
+	for _, diagnostic := range t.Inspect(input) {
+		if diagnostic.Severity == SeverityError {
+			errs = append(errs, diagnostic)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Diagnostics: errs}
+}
+
+// Inspect reports every problem found in text meant for FromPejelagarto with the translator's options
+// Checks of disabled stages are skipped
+func (t *Translator) Inspect(input string) []Diagnostic {
+	rules, err := t.decodingRules(input)
+	if err != nil {
+		return []Diagnostic{{Code: DiagnosticRuleset, Severity: SeverityError, Position: -1, Message: err.Error()}}
+	}
+	runes := []rune(input)
+	var diagnostics []Diagnostic
+	lossless := false
+
+	// Positions of the runes left once the timestamp characters are removed, as the decoder sees them
+	positions := make([]int, 0, len(runes))
+	stripped := make([]rune, 0, len(runes))
+	if t.opts.DisableTimestamp {
+		for i, r := range runes {
+			positions = append(positions, i)
+			stripped = append(stripped, r)
+		}
+	} else {
+		var hidden []bool
+		hidden, lossless = hiddenTimestampMask(runes)
+		diagnostics = inspectTimestamp(runes, hidden, diagnostics)
+		diagnostics = t.inspectRulesetVersion(input, rules, diagnostics)
+		if err := t.checkTextStages(input); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     DiagnosticStages,
+				Severity: SeverityError,
+				Position: -1,
+				Message:  fmt.Sprintf("%v, nothing decodes", err),
+			})
+		}
+		for i, r := range runes {
+			if !hidden[i] {
+				positions = append(positions, i)
+				stripped = append(stripped, r)
+			}
+		}
+	}
+
+	// The contents of protected spans are not translated, the decoder only sees their delimiters
+	protected := t.protectedSpans()
+	if spans := findProtectedSpans(stripped, protected.open, protected.close); len(spans) > 0 {
+		keptPositions := make([]int, 0, len(positions))
+		kept := make([]rune, 0, len(stripped))
+		next := 0
+		for _, span := range spans {
+			keptPositions = append(keptPositions, positions[next:span[0]]...)
+			kept = append(kept, stripped[next:span[0]]...)
+			next = span[1]
+		}
+		positions = append(keptPositions, positions[next:]...)
+		stripped = append(kept, stripped[next:]...)
+	}
+
+	// Quotes are escaped once by each of the punctuation and map replacement stages
+	escapeLayers := 0
+	if !t.opts.DisablePunctuation {
+		escapeLayers++
+	}
+	if !t.opts.DisableMapReplacements {
+		escapeLayers++
+	}
+	diagnostics = inspectEscapes(stripped, positions, escapeLayers, !t.opts.DisablePunctuation, lossless, diagnostics)
+	if !t.opts.DisableAccents {
+		vowels := factorVowelsLocally(stripped, rules.wheels, t.locality(input), rules.sentenceTerminators())
+		diagnostics = inspectAccents(stripped, positions, vowels, diagnostics)
+	}
+	diagnostics = inspectUTF8Sentinels(stripped, positions, diagnostics)
+	return diagnostics
+}
+
+// timestampComponent describes one component of the hidden timestamp
+type timestampComponent struct {
+	name     string
+	chars    []string
+	required bool
+	sequence bool // several characters of the component are expected
+}
+
+// timestampSpecialChars maps each timestamp character to the index of its component
+func timestampSpecialChars() map[rune]int {
+	chars := make(map[rune]int)
+	for component, c := range timestampComponents() {
+		for _, char := range c.chars {
+			chars[[]rune(char)[0]] = component
+		}
+	}
+	return chars
+}
+
+// timestampComponents lists the timestamp components in the order they are encoded
+// The authentication tag, the normalization, locality and lossless markers, the ruleset version and the
+// metadata are hidden the same way, so they are listed too
+func timestampComponents() []timestampComponent {
+	return []timestampComponent{
+		{"day", DaySpecialCharIndex, true, false},
+		{"month", MonthSpecialCharIndex, true, false},
+		{"year", YearSpecialCharIndex, true, false},
+		{"hour", HourSpecialCharIndex, false, false},
+		{"minute", MinuteSpecialCharIndex, false, false},
+		{"version 2 marker", []string{TimestampV2Marker}, false, false},
+		{"version 2 digit", TimestampV2DigitIndex, false, true},
+		{"signature marker", []string{SignatureMarker}, false, false},
+		{"signature digit", SignatureDigitIndex, false, true},
+		{"normalization marker", []string{NormalizationMarker}, false, false},
+		{"locality marker", []string{SentenceLocalityMarker, ParagraphLocalityMarker}, false, false},
+		{"lossless marker", []string{LosslessMarker}, false, false},
+		{"ruleset version marker", []string{RulesetVersionMarker}, false, false},
+		{"ruleset version digit", RulesetVersionDigitIndex, false, true},
+		{"metadata marker", []string{MetadataMarker}, false, false},
+		{"metadata digit", MetadataDigitIndex, false, true},
+	}
+}
+
+// inspectTimestamp checks that every required component is present exactly once
+// Only the runes set in hidden are read, see hiddenTimestampMask
+func inspectTimestamp(runes []rune, hidden []bool, diagnostics []Diagnostic) []Diagnostic {
+	components := timestampComponents()
+	specialChars := timestampSpecialChars()
+
+	found := make([][]int, len(components)) // component -> positions of its characters
+	total := 0
+	var hiddenChars []rune
+	for i, r := range runes {
+		if !hidden[i] {
+			continue
+		}
+		found[specialChars[r]] = append(found[specialChars[r]], i)
+		hiddenChars = append(hiddenChars, r)
+		total++
+	}
+
+	if total == 0 {
+		return append(diagnostics, Diagnostic{
+			Code:     DiagnosticTimestampMissing,
+			Severity: SeverityWarning,
+			Position: -1,
+			Message:  "no timestamp characters found, the text may be Human text or was translated without a timestamp",
+		})
+	}
+
+	for component, positions := range found {
+		c := components[component]
+		if len(positions) == 0 {
+			if c.required {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticTimestampIncomplete,
+					Severity: SeverityError,
+					Position: -1,
+					Message:  fmt.Sprintf("no %s character, the timestamp cannot be decoded", c.name),
+				})
+			}
+			continue
+		}
+		if c.sequence {
+			continue
+		}
+		first := runes[positions[0]]
+		for _, pos := range positions[1:] {
+			if runes[pos] != first {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticTimestampConflict,
+					Severity: SeverityError,
+					Position: pos,
+					Message:  fmt.Sprintf("%s character %q conflicts with %q at rune %d", c.name, runes[pos], first, positions[0]),
+				})
+			}
+		}
+	}
+
+	// Version 1 texts have no extension; a marker promises exactly the digits the encoder writes
+	if strings.Contains(string(hiddenChars), TimestampV2Marker) {
+		if _, _, _, ok := readTimestampV2Extension(string(hiddenChars)); !ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     DiagnosticTimestampExtension,
+				Severity: SeverityError,
+				Position: -1,
+				Message:  "damaged version 2 timestamp digits, the seconds, offset and full year cannot be decoded",
+			})
+		}
+	}
+	if _, err := readMetadata(string(hiddenChars)); errors.Is(err, ErrDamagedMetadata) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Code:     DiagnosticMetadata,
+			Severity: SeverityError,
+			Position: -1,
+			Message:  fmt.Sprintf("%v, the metadata cannot be decoded", err),
+		})
+	}
+	return diagnostics
+}
+
+// inspectRulesetVersion checks that the dialect version a text carries is known
+func (t *Translator) inspectRulesetVersion(input string, rules *compiledRules, diagnostics []Diagnostic) []Diagnostic {
+	if t.compiled != nil {
+		return diagnostics
+	}
+	hidden, _, _ := splitHiddenTimestampChars(input)
+	version := readRulesetVersion(hidden)
+	if version == "" || rules.fingerprint == version {
+		return diagnostics
+	}
+	return append(diagnostics, Diagnostic{
+		Code:     DiagnosticRulesetVersion,
+		Severity: SeverityWarning,
+		Position: -1,
+		Message:  fmt.Sprintf("written with unknown ruleset version %s, decoded with the current dialect", version),
+	})
+}
+
+// inspectEscapes checks each layer of soft hyphen escaping, outermost first
+// Every escape must be followed by a quote or by another escape character, or in the innermost layer
+// by the punctuation of a number when numberPunctuation is set (the punctuation stage escapes it)
+// Lossless texts have one more layer inside them, escaping the timestamp characters of the Human text
+func inspectEscapes(runes []rune, positions []int, layers int, numberPunctuation, lossless bool, diagnostics []Diagnostic) []Diagnostic {
+	specialChars := timestampSpecialChars()
+	total := layers
+	if lossless {
+		total++
+	}
+	for layer := 0; layer < total; layer++ {
+		var unescaped []rune
+		var unescapedPositions []int
+		for i := 0; i < len(runes); i++ {
+			if runes[i] != OutputEscapeChar {
+				unescaped = append(unescaped, runes[i])
+				unescapedPositions = append(unescapedPositions, positions[i])
+				continue
+			}
+			if i+1 == len(runes) {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticDanglingEscape,
+					Severity: SeverityError,
+					Position: positions[i],
+					Message:  "escape character at the end of the text escapes nothing",
+				})
+				break
+			}
+			innermost := layer == layers-1
+			next := runes[i+1]
+			_, isSpecial := specialChars[next]
+			if layer == layers && !isSpecial && next != OutputEscapeChar {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticDanglingEscape,
+					Severity: SeverityError,
+					Position: positions[i],
+					Message:  fmt.Sprintf("escape character before %q, only timestamp characters and escape characters are escaped in lossless texts", next),
+				})
+			} else if layer < layers && next != '\'' && next != OutputEscapeChar && !(innermost && numberPunctuation && isNumberPunctuation(next)) {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticDanglingEscape,
+					Severity: SeverityError,
+					Position: positions[i],
+					Message:  fmt.Sprintf("escape character before %q, only quotes, escape characters and the punctuation of numbers are escaped", next),
+				})
+			}
+			i++
+			unescaped = append(unescaped, runes[i])
+			unescapedPositions = append(unescapedPositions, positions[i])
+		}
+		runes, positions = unescaped, unescapedPositions
+	}
+	return diagnostics
+}
+
+// factorVowel is a vowel selected by a prime factor of the cluster count
+type factorVowel struct {
+	pos      int  // index in the runes of the first rune of the vowel
+	prime    int  // the vowel's 1-based index among the vowels
+	shift    int  // steps the encoder moves it along its wheel, never 0
+	accented bool // not the base form of its wheel
+}
+
+// factorVowels returns the vowels the accent stage moves, in prime order
+// Vowels whose move is a whole turn of their wheel are left out, as their accent carries no signal
+func factorVowels(runes []rune, wheels accentWheels) []factorVowel {
+	starts := accentClusters(runes)
+	var vowels []string
+	var vowelPositions []int
+	for i, start := range starts {
+		end := len(runes)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		if wheels.isVowel(runes[start:end]) {
+			vowels = append(vowels, lowerCluster(runes[start:end]))
+			vowelPositions = append(vowelPositions, start)
+		}
+	}
+
+	factors := primeFactorize(len(starts))
+	primes := make([]int, 0, len(factors))
+	for prime := range factors {
+		primes = append(primes, prime)
+	}
+	sort.Ints(primes)
+
+	var selected []factorVowel
+	for _, prime := range primes {
+		vowelIndex := prime - 1
+		if vowelIndex >= len(vowels) {
+			continue
+		}
+		wheel := wheels.wheels[wheels.bases[vowels[vowelIndex]]]
+		if factors[prime]%len(wheel) == 0 {
+			continue
+		}
+		selected = append(selected, factorVowel{
+			pos:      vowelPositions[vowelIndex],
+			prime:    prime,
+			shift:    factors[prime] % len(wheel),
+			accented: vowels[vowelIndex] != wheel[0],
+		})
+	}
+	return selected
+}
+
+// inspectAccents checks the vowels selected by the prime factors of the cluster count
+// The encoder moves each of them along its wheel, so an unaccented vowel there usually means
+// the text was not produced by the encoder
+func inspectAccents(runes []rune, positions []int, vowels []factorVowel, diagnostics []Diagnostic) []Diagnostic {
+	for _, vowel := range vowels {
+		if !vowel.accented {
+			diagnostics = append(diagnostics, Diagnostic{
+				Code:     DiagnosticAccentPosition,
+				Severity: SeverityWarning,
+				Position: positions[vowel.pos],
+				Message: fmt.Sprintf("vowel %q (vowel %d) is unaccented, but the encoder moves it %d steps along its accent wheel",
+					runes[vowel.pos], vowel.prime, vowel.shift),
+			})
+		}
+	}
+	return diagnostics
+}
+
+// inspectUTF8Sentinels checks the Hangul Filler + Private Use Area pairs that encode invalid UTF-8 bytes
+// A pair only round-trips if its byte is still invalid UTF-8 where it is decoded
+func inspectUTF8Sentinels(runes []rune, positions []int, diagnostics []Diagnostic) []Diagnostic {
+	const privateUseStart = 0xE000
+	const hangulFiller = '\u3164'
+
+	isPair := func(i int) bool {
+		return i+1 < len(runes) && runes[i] == hangulFiller &&
+			runes[i+1] >= privateUseStart && runes[i+1] < privateUseStart+256
+	}
+
+	for i := 0; i < len(runes); i++ {
+		if !isPair(i) {
+			continue
+		}
+		// Decode the run of pairs starting here, followed by the text after it
+		var decoded []byte
+		var starts []int
+		j := i
+		for ; isPair(j); j += 2 {
+			starts = append(starts, j)
+			decoded = append(decoded, byte(runes[j+1]-privateUseStart))
+		}
+		runLen := len(decoded)
+		decoded = append(decoded, string(runes[j:min(j+utf8.UTFMax, len(runes))])...)
+
+		for k := 0; k < runLen; k++ {
+			if r, size := utf8.DecodeRune(decoded[k:]); r != utf8.RuneError || size != 1 {
+				diagnostics = append(diagnostics, Diagnostic{
+					Code:     DiagnosticUTF8Sentinel,
+					Severity: SeverityError,
+					Position: positions[starts[k]],
+					Message:  fmt.Sprintf("sentinel pair encodes byte 0x%02X, which is valid UTF-8 here and would not round-trip", decoded[k]),
+				})
+			}
+		}
+		i = j - 1
+	}
+	return diagnostics
+}
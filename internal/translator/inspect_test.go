@@ -0,0 +1,73 @@
+package translator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// FuzzValidateTranslatorOutput tests that text produced by the translator never has error diagnostics
+func FuzzValidateTranslatorOutput(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("")
+	f.Add("Hello, World! It's 'quoted' text\u00AD with -42 and 3.14")
+	f.Add("aeiou AEIOU àéîõü\n2025-10-19T14:30:00Z")
+	f.Fuzz(func(t *testing.T, input string) {
+		// Literal sentinel pairs in Human text are a known limitation of the invalid UTF-8 encoding
+		if strings.ContainsRune(input, '\u3164') {
+			return
+		}
+
+		pejelagarto := TranslateToPejelagarto(input)
+		if err := Validate(pejelagarto); err != nil {
+			t.Errorf("Validate() reported errors on translator output\nInput:       %q\nPejelagarto: %q\nError: %v", input, pejelagarto, err)
+		}
+	})
+}
+
+// fixedInspectTime is hidden in the output used to build damaged texts
+var fixedInspectTime = time.Date(2026, time.March, 14, 15, 9, 0, 0, time.UTC)
+
+// TestInspectReportsDamage verifies each kind of damage is reported with its code and severity
+func TestInspectReportsDamage(t *testing.T) {
+	tr := fixedTestTranslator(Options{Clock: FixedClock(fixedInspectTime)})
+	valid := tr.ToPejelagarto("Hello there, it's a test")
+
+	tests := []struct {
+		name     string
+		input    string
+		code     string
+		severity Severity
+	}{
+		{"plain text", "hello world", DiagnosticTimestampMissing, SeverityWarning},
+		{"missing day", strings.ReplaceAll(valid, DaySpecialCharIndex[fixedInspectTime.Day()-1], ""), DiagnosticTimestampIncomplete, SeverityError},
+		{"conflicting day", valid + DaySpecialCharIndex[fixedInspectTime.Day()], DiagnosticTimestampConflict, SeverityError},
+		{"dangling escape", valid + "\u00AD", DiagnosticDanglingEscape, SeverityError},
+		{"escaped letter", "\u00ADa" + valid, DiagnosticDanglingEscape, SeverityError},
+		{"valid UTF-8 sentinel", valid + "\u3164\uE041", DiagnosticUTF8Sentinel, SeverityError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found bool
+			for _, diagnostic := range Inspect(tt.input) {
+				if diagnostic.Code == tt.code && diagnostic.Severity == tt.severity {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Inspect(%q) = %v, want a %s %s", tt.input, Inspect(tt.input), tt.severity, tt.code)
+			}
+
+			var validationErr *ValidationError
+			if isError := errors.As(Validate(tt.input), &validationErr); isError != (tt.severity == SeverityError) {
+				t.Errorf("Validate(%q) error = %v, want error: %v", tt.input, validationErr, tt.severity == SeverityError)
+			}
+		})
+	}
+
+	if diagnostics := Inspect(tr.ToPejelagarto("\xff\xfe")); len(diagnostics) != 0 {
+		t.Errorf("Inspect() of encoded invalid UTF-8 = %v, want no diagnostics", diagnostics)
+	}
+}
@@ -0,0 +1,132 @@
+package translator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Locality
+// The accent and consonant stages count the clusters of the whole text and the case stage its words,
+// so typing one character moves accents and case all over the output. With Options.Locality set to
+// LocalitySentence or LocalityParagraph the three stages run on every sentence or paragraph on its
+// own, and a marker hidden with the timestamp characters tells the decoder to do the same: an edit
+// only changes the output of the sentence or paragraph it is in
+// Segments end at characters the three stages never change (line breaks, and for sentences a
+// terminator followed by spaces), so the encoder and the decoder cut the text at the same places
+// The marker is part of the timestamp stage; with DisableTimestamp the decoder uses Options.Locality
+
+// Locality selects the span of text the length-dependent stages work on
+type Locality string
+
+const (
+	// LocalityText runs the stages on the whole text, the default
+	LocalityText Locality = ""
+	// LocalitySentence runs the stages on every sentence; a sentence ends after a line break or
+	// after a terminator (e.g. '.', '!', '?' or their Pejelagarto forms) and the spaces following it
+	LocalitySentence Locality = "sentence"
+	// LocalityParagraph runs the stages on every paragraph; a paragraph ends after a line break
+	LocalityParagraph Locality = "paragraph"
+)
+
+// Markers recording the locality of the encoder
+var (
+	SentenceLocalityMarker  = "⏥"
+	ParagraphLocalityMarker = "⏦"
+)
+
+// marker returns the marker hidden for a locality, "" for the whole text
+func (l Locality) marker() string {
+	switch l {
+	case LocalitySentence:
+		return SentenceLocalityMarker
+	case LocalityParagraph:
+		return ParagraphLocalityMarker
+	}
+	return ""
+}
+
+// locality returns the locality the encoder of a Pejelagarto text used
+func (t *Translator) locality(input string) Locality {
+	if t.opts.DisableTimestamp {
+		return t.opts.Locality
+	}
+	hidden, _, _ := splitHiddenTimestampChars(input)
+	switch {
+	case strings.Contains(hidden, SentenceLocalityMarker):
+		return LocalitySentence
+	case strings.Contains(hidden, ParagraphLocalityMarker):
+		return LocalityParagraph
+	}
+	return LocalityText
+}
+
+// sentenceTerminators returns the runes that end a sentence, in Human and in the dialect
+func (c *compiledRules) sentenceTerminators() string {
+	terminators := ".!?…"
+	for _, key := range []string{".", "!", "?"} {
+		terminators += c.punctuation[key]
+	}
+	return terminators
+}
+
+// localSegments splits text into the segments the length-dependent stages work on
+// Joined, the segments give the text back
+func localSegments(text string, locality Locality, terminators string) []string {
+	if locality != LocalitySentence && locality != LocalityParagraph {
+		return []string{text}
+	}
+
+	runes := []rune(text)
+	var segments []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		end := 0
+		switch {
+		case runes[i] == '\n':
+			end = i + 1
+		case locality == LocalitySentence && strings.ContainsRune(terminators, runes[i]) &&
+			i+1 < len(runes) && unicode.IsSpace(runes[i+1]):
+			// The sentence keeps the spaces after it, line breaks included
+			end = i + 1
+			for end < len(runes) && unicode.IsSpace(runes[end]) {
+				end++
+			}
+		}
+		if end > 0 {
+			segments = append(segments, string(runes[start:end]))
+			start = end
+			i = end - 1
+		}
+	}
+	if start < len(runes) {
+		segments = append(segments, string(runes[start:]))
+	}
+	return segments
+}
+
+// factorVowelsLocally returns the vowels the accent stage moves in every segment, positioned in runes
+func factorVowelsLocally(runes []rune, wheels accentWheels, locality Locality, terminators string) []factorVowel {
+	var vowels []factorVowel
+	offset := 0
+	for _, segment := range localSegments(string(runes), locality, terminators) {
+		segmentRunes := []rune(segment)
+		for _, vowel := range factorVowels(segmentRunes, wheels) {
+			vowel.pos += offset
+			vowels = append(vowels, vowel)
+		}
+		offset += len(segmentRunes)
+	}
+	return vowels
+}
+
+// applyLocally applies a length-dependent stage to every segment of input on its own
+func applyLocally(input string, stage func(string) string, locality Locality, terminators string) string {
+	if locality == LocalityText {
+		return stage(input)
+	}
+	var result strings.Builder
+	for _, segment := range localSegments(input, locality, terminators) {
+		result.WriteString(stage(segment))
+	}
+	return result.String()
+}
@@ -0,0 +1,68 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzLocalityRoundTrip tests that sentence and paragraph localities round-trip, with and without the marker
+func FuzzLocalityRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Hello world. How are you? Fine!\nNext paragraph, same line.  Two spaces")
+	f.Add("3.14 is not a sentence end... but this is. ¡Hola! ‽ \n\n\nshell chat")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+		input = RemoveTimestampSpecialCharacters(input)
+		inputCleaned, _ := removeISO8601timestamp(input)
+
+		for _, locality := range []Locality{LocalitySentence, LocalityParagraph} {
+			tr := fixedTestTranslator(Options{Locality: locality})
+			pejelagarto := tr.ToPejelagarto(input)
+			// The decoder reads the locality from the marker
+			reversed, _ := removeISO8601timestamp(New(Options{}).FromPejelagarto(pejelagarto))
+			if reversed != inputCleaned {
+				t.Errorf("%s round-trip failed\nInput:       %q\nPejelagarto: %q\nReversed:    %q", locality, inputCleaned, pejelagarto, reversed)
+			}
+
+			checkRoundTrip(t, New(Options{Locality: locality, DisableTimestamp: true}), input)
+		}
+	})
+}
+
+// TestLocality verifies an edit only changes the output of its own sentence or paragraph
+func TestLocality(t *testing.T) {
+	tests := []struct {
+		locality     Locality
+		before       string
+		after        string
+		keptSegments int // leading segments whose output must not change
+	}{
+		{LocalitySentence, "The cat sleeps. A dog barks! Birds sing", "The cat sleeps. A dog barks! Birds sing loudly", 2},
+		{LocalityParagraph, "First line here\nSecond line\nThird", "First line here\nSecond line\nThird line", 2},
+	}
+	for _, tt := range tests {
+		tr := New(Options{Locality: tt.locality, DisableTimestamp: true})
+		before, after := tr.ToPejelagarto(tt.before), tr.ToPejelagarto(tt.after)
+		terminators := currentRules(t).sentenceTerminators()
+		beforeSegments := localSegments(before, tt.locality, terminators)
+		afterSegments := localSegments(after, tt.locality, terminators)
+		for i := 0; i < tt.keptSegments; i++ {
+			if beforeSegments[i] != afterSegments[i] {
+				t.Errorf("%s: segment %d changed from %q to %q", tt.locality, i, beforeSegments[i], afterSegments[i])
+			}
+		}
+
+		whole := New(Options{DisableTimestamp: true})
+		if got := whole.ToPejelagarto(tt.before); got == before {
+			t.Errorf("%s: translating %q locally gave the same output as the whole text", tt.locality, tt.before)
+		}
+	}
+
+	pejelagarto := New(Options{Locality: LocalitySentence}).ToPejelagarto("One. Two.")
+	if !strings.Contains(pejelagarto, SentenceLocalityMarker) {
+		t.Errorf("ToPejelagarto() = %q, want the sentence locality marker", pejelagarto)
+	}
+}
@@ -0,0 +1,85 @@
+package translator
+
+import "strings"
+
+// Lossless mode
+// The encoder deletes the timestamp characters it finds in the Human text, since the decoder could
+// not tell them from the ones it hides. With Options.Lossless they are escaped with OutputEscapeChar
+// instead, like quotes, and LosslessMarker is hidden with the timestamp characters: the decoder then
+// only reads and removes the unescaped ones, and unescapes its output, so every input round-trips
+// Hidden characters are never placed after OutputEscapeChar (see insertSpecialChars), so they never
+// look escaped. Text without timestamp characters is translated as without the option
+// The marker is part of the timestamp stage; with DisableTimestamp nothing is removed anyway
+
+// LosslessMarker records that the timestamp characters of the Human text were escaped
+var LosslessMarker = "⏧"
+
+// escapeTimestampSpecialCharacters escapes the timestamp characters of Human text
+// It reports false, leaving input unchanged, when there are none
+func escapeTimestampSpecialCharacters(input string) (string, bool) {
+	specialChars := timestampSpecialChars()
+	found := false
+	for _, r := range input {
+		if _, isSpecial := specialChars[r]; isSpecial {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return input, false
+	}
+
+	var chars strings.Builder
+	for r := range specialChars {
+		chars.WriteRune(r)
+	}
+	return outputEscape(input, chars.String()), true
+}
+
+// hiddenTimestampMask reports which runes are timestamp characters hidden by the encoder, and
+// whether the text is lossless; in a lossless text the escaped ones belong to the Human text
+func hiddenTimestampMask(runes []rune) ([]bool, bool) {
+	specialChars := timestampSpecialChars()
+	marker := []rune(LosslessMarker)[0]
+	lossless := false
+	for i, r := range runes {
+		if r == marker && !escapedAt(runes, i) {
+			lossless = true
+			break
+		}
+	}
+
+	hidden := make([]bool, len(runes))
+	for i, r := range runes {
+		if _, isSpecial := specialChars[r]; isSpecial {
+			hidden[i] = !lossless || !escapedAt(runes, i)
+		}
+	}
+	return hidden, lossless
+}
+
+// escapedAt reports whether the rune at i follows an escape character
+func escapedAt(runes []rune, i int) bool {
+	return i > 0 && runes[i-1] == OutputEscapeChar
+}
+
+// splitHiddenTimestampChars separates the timestamp characters hidden in Pejelagarto text from the rest
+// Outside lossless texts every timestamp character is hidden and hidden is the whole input, which
+// the readers of the timestamp characters accept as it is
+func splitHiddenTimestampChars(input string) (hidden, rest string, lossless bool) {
+	runes := []rune(input)
+	mask, lossless := hiddenTimestampMask(runes)
+	if !lossless {
+		return input, RemoveTimestampSpecialCharacters(input), false
+	}
+
+	var hiddenChars, restChars strings.Builder
+	for i, r := range runes {
+		if mask[i] {
+			hiddenChars.WriteRune(r)
+		} else {
+			restChars.WriteRune(r)
+		}
+	}
+	return hiddenChars.String(), restChars.String(), true
+}
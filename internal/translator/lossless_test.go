@@ -0,0 +1,74 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzLosslessRoundTrip tests that any text, timestamp characters included, round-trips in lossless mode
+func FuzzLosslessRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Meeting at ⌚ in room ⌀­⌁, see ⏤ and ⏧")
+	f.Add("­⌀ ­­⏥\n'quoted' 3.14 ⏁⏂⏃")
+	f.Add("é゙〪 a­")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+		inputCleaned, _ := removeISO8601timestamp(input)
+
+		tr := fixedTestTranslator(Options{Lossless: true, Key: []byte("key")})
+		pejelagarto := tr.ToPejelagarto(input)
+		// The decoder reads the mode from the marker
+		reversed, verification, _ := tr.FromPejelagartoVerifiedContext(t.Context(), pejelagarto)
+		if reversed, _ = removeISO8601timestamp(reversed); reversed != inputCleaned {
+			t.Errorf("round-trip failed\nInput:       %q\nPejelagarto: %q\nReversed:    %q", inputCleaned, pejelagarto, reversed)
+		}
+		if verification != VerificationAuthentic {
+			t.Errorf("verification = %q, want %q\nInput: %q", verification, VerificationAuthentic, input)
+		}
+		if extracted, err := ExtractTimestamp(pejelagarto); err != nil || !extracted.Time.Equal(tr.opts.Timestamp) || extracted.Tampered {
+			t.Errorf("ExtractTimestamp = %+v, %v\nInput: %q", extracted, err, input)
+		}
+	})
+}
+
+// TestLossless verifies the timestamp characters of the Human text are kept and ignored by the readers
+func TestLossless(t *testing.T) {
+	lossless := fixedTestTranslator(Options{Lossless: true, Rand: SeededRand(7)})
+	lossy := fixedTestTranslator(Options{Rand: SeededRand(7)})
+
+	// Text without timestamp characters translates as without the option
+	plain := "Hello world, 'quoted' ­ text"
+	if got, want := lossless.ToPejelagarto(plain), lossy.ToPejelagarto(plain); got != want {
+		t.Errorf("lossless output of plain text = %q, want %q", got, want)
+	}
+
+	// A literal day character and locality marker are kept and do not change the decoding
+	input := "Day " + DaySpecialCharIndex[0] + " and " + SentenceLocalityMarker + " marker. Next sentence"
+	pejelagarto := lossless.ToPejelagarto(input)
+	if !strings.Contains(pejelagarto, LosslessMarker) {
+		t.Fatalf("no lossless marker in %q", pejelagarto)
+	}
+	reversed, _ := removeISO8601timestamp(New(Options{}).FromPejelagarto(pejelagarto))
+	if reversed != input {
+		t.Errorf("round-trip = %q, want %q", reversed, input)
+	}
+	if got := lossless.locality(pejelagarto); got != LocalityText {
+		t.Errorf("locality = %q, want the whole text", got)
+	}
+	extracted, err := ExtractTimestamp(pejelagarto)
+	if err != nil || !extracted.Time.Equal(fixedTestTime) || extracted.Tampered {
+		t.Errorf("ExtractTimestamp = %+v, %v, want %v untampered", extracted, err, fixedTestTime)
+	}
+	if diagnostics := lossless.Inspect(pejelagarto); len(diagnostics) > 0 {
+		t.Errorf("Inspect reported %+v", diagnostics)
+	}
+
+	// Without the option the characters are removed as before
+	reversed, _ = removeISO8601timestamp(New(Options{}).FromPejelagarto(lossy.ToPejelagarto(input)))
+	if want := RemoveTimestampSpecialCharacters(input); reversed != want {
+		t.Errorf("lossy round-trip = %q, want %q", reversed, want)
+	}
+}
@@ -0,0 +1,221 @@
+package translator
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata channel
+// Options.Metadata hides key/value pairs (author, message ID, source language, reply-to...) next to
+// the timestamp characters: MetadataMarker followed by the hexadecimal digits of the encoded pairs,
+// from MetadataDigitIndex, most significant first. The encoding is compact binary:
+//
+//	pair*  checksum (1 byte, the low byte of the CRC-32 of the pairs)
+//	pair:  key code (uvarint) | [key length (uvarint) | key] | value header (uvarint) | [value]
+//
+// Key codes 1-4 stand for the well-known keys, 0 for a key written out after it. A value header
+// 2n+1 is the decimal integer n, written as strconv.FormatUint writes it; 2n is a string of n bytes
+// Pairs are sorted by key, so equal metadata always gives the same characters
+// The metadata is part of the timestamp stage; with DisableTimestamp nothing is hidden
+
+// Metadata holds the key/value pairs hidden in a Pejelagarto text
+type Metadata map[string]string
+
+// Well-known metadata keys, encoded in a single byte
+const (
+	MetadataAuthor         = "author"
+	MetadataMessage        = "message"
+	MetadataSourceLanguage = "source_language"
+	MetadataReplyTo        = "reply_to"
+)
+
+// metadataKeys lists the well-known keys by key code, code 0 being a key written out
+var metadataKeys = []string{"", MetadataAuthor, MetadataMessage, MetadataSourceLanguage, MetadataReplyTo}
+
+// MetadataMarker announces the hidden metadata
+var MetadataMarker = "⏩"
+
+// MetadataDigitIndex holds the 16 digits of the encoded metadata
+var MetadataDigitIndex = []string{
+	"␐", "␑", "␒", "␓", "␔", "␕", "␖", "␗",
+	"␘", "␙", "␚", "␛", "␜", "␝", "␞", "␟",
+}
+
+// MaxMetadataOptionBytes bounds the encoded size of the metadata ParseOptions accepts;
+// every byte is hidden as two characters
+const MaxMetadataOptionBytes = 256
+
+var (
+	// ErrNoMetadata is returned by ExtractMetadata when a text carries no metadata
+	ErrNoMetadata = errors.New("no embedded metadata")
+	// ErrDamagedMetadata is returned when the metadata characters are not those written by the encoder
+	ErrDamagedMetadata = errors.New("damaged embedded metadata")
+)
+
+// EncodeMetadata returns the compact binary encoding of the pairs, nil when there are none
+func EncodeMetadata(m Metadata) []byte {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var data []byte
+	for _, key := range keys {
+		if code := slices.Index(metadataKeys, key); code > 0 {
+			data = binary.AppendUvarint(data, uint64(code))
+		} else {
+			data = binary.AppendUvarint(data, 0)
+			data = binary.AppendUvarint(data, uint64(len(key)))
+			data = append(data, key...)
+		}
+
+		value := m[key]
+		if n, isInteger := metadataInteger(value); isInteger {
+			data = binary.AppendUvarint(data, n<<1|1)
+		} else {
+			data = binary.AppendUvarint(data, uint64(len(value))<<1)
+			data = append(data, value...)
+		}
+	}
+	return append(data, byte(crc32.ChecksumIEEE(data)))
+}
+
+// DecodeMetadata decodes the output of EncodeMetadata, returning ErrDamagedMetadata for anything else
+func DecodeMetadata(data []byte) (Metadata, error) {
+	if len(data) == 0 {
+		return Metadata{}, nil
+	}
+	data, checksum := data[:len(data)-1], data[len(data)-1]
+	if byte(crc32.ChecksumIEEE(data)) != checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrDamagedMetadata)
+	}
+
+	read := func() (uint64, bool) {
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, false
+		}
+		data = data[n:]
+		return value, true
+	}
+	readBytes := func(length uint64) (string, bool) {
+		if length > uint64(len(data)) {
+			return "", false
+		}
+		s := string(data[:length])
+		data = data[length:]
+		return s, true
+	}
+
+	m := make(Metadata)
+	for len(data) > 0 {
+		code, ok := read()
+		if !ok || code >= uint64(len(metadataKeys)) {
+			return nil, fmt.Errorf("%w: bad key code", ErrDamagedMetadata)
+		}
+		key := metadataKeys[code]
+		if code == 0 {
+			length, ok := read()
+			if ok {
+				key, ok = readBytes(length)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%w: truncated key", ErrDamagedMetadata)
+			}
+		}
+		if _, duplicate := m[key]; duplicate {
+			return nil, fmt.Errorf("%w: duplicate key %q", ErrDamagedMetadata, key)
+		}
+
+		header, ok := read()
+		if !ok {
+			return nil, fmt.Errorf("%w: truncated value of %q", ErrDamagedMetadata, key)
+		}
+		if header&1 == 1 {
+			m[key] = strconv.FormatUint(header>>1, 10)
+			continue
+		}
+		if m[key], ok = readBytes(header >> 1); !ok {
+			return nil, fmt.Errorf("%w: truncated value of %q", ErrDamagedMetadata, key)
+		}
+	}
+	return m, nil
+}
+
+// metadataInteger reports whether value is a decimal integer that EncodeMetadata writes as a number
+// Only the form strconv.FormatUint gives back is, so "007" stays a string
+func metadataInteger(value string) (uint64, bool) {
+	n, err := strconv.ParseUint(value, 10, 63)
+	if err != nil || strconv.FormatUint(n, 10) != value {
+		return 0, false
+	}
+	return n, true
+}
+
+// metadataChars returns the characters hiding encoded metadata
+func metadataChars(data []byte) []string {
+	chars := []string{MetadataMarker}
+	for _, b := range data {
+		chars = append(chars, MetadataDigitIndex[b>>4], MetadataDigitIndex[b&0x0F])
+	}
+	return chars
+}
+
+// readMetadataDigits returns every metadata digit hidden in input as hexadecimal text, and whether
+// a marker is present; the digits are not checked, see readMetadata
+func readMetadataDigits(hidden string) (digits string, present bool) {
+	if !strings.Contains(hidden, MetadataMarker) {
+		return "", false
+	}
+	digitValues := make(map[rune]int, len(MetadataDigitIndex))
+	for i, digit := range MetadataDigitIndex {
+		digitValues[[]rune(digit)[0]] = i
+	}
+	var hexDigits strings.Builder
+	for _, r := range hidden {
+		if value, isDigit := digitValues[r]; isDigit {
+			hexDigits.WriteByte("0123456789abcdef"[value])
+		}
+	}
+	return hexDigits.String(), true
+}
+
+// readMetadata decodes the metadata hidden in the timestamp characters of a text, see ExtractMetadata
+func readMetadata(hidden string) (Metadata, error) {
+	digits, present := readMetadataDigits(hidden)
+	if !present {
+		return nil, ErrNoMetadata
+	}
+	if strings.Count(hidden, MetadataMarker) > 1 {
+		return nil, fmt.Errorf("%w: several markers", ErrDamagedMetadata)
+	}
+	// The encoder inserts the digits after the marker
+	if strings.ContainsAny(hidden[:strings.Index(hidden, MetadataMarker)], strings.Join(MetadataDigitIndex, "")) {
+		return nil, fmt.Errorf("%w: digits before the marker", ErrDamagedMetadata)
+	}
+	data, err := hex.DecodeString(digits)
+	if err != nil || len(data) == 0 {
+		// The encoder only hides a marker with some pairs
+		return nil, fmt.Errorf("%w: %d digits", ErrDamagedMetadata, len(digits))
+	}
+	return DecodeMetadata(data)
+}
+
+// ExtractMetadata reads the metadata hidden in a Pejelagarto text without translating it
+// It returns ErrNoMetadata when there is none and an error wrapping ErrDamagedMetadata when
+// its characters were altered. The escaped characters of lossless texts are part of the Human text
+func ExtractMetadata(text string) (Metadata, error) {
+	hidden, _, _ := splitHiddenTimestampChars(text)
+	return readMetadata(hidden)
+}
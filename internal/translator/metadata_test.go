@@ -0,0 +1,123 @@
+package translator
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzMetadataRoundTrip tests that hidden metadata is read back, signed, and leaves the translation unchanged
+func FuzzMetadataRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("", "author", "ana", "topic", "")
+	f.Add("Hello, World! 123\n2025-10-19T14:30:45+05:30", "message", "1001", "reply_to", "0998")
+	f.Add("Línea ⏩ ␐ uno.\nDos", "source_language", "es", "", "9223372036854775807")
+	f.Fuzz(func(t *testing.T, input, key1, value1, key2, value2 string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+		metadata := Metadata{key1: value1, key2: value2}
+
+		tagged := fixedTestTranslator(Options{Key: []byte("key"), Metadata: metadata, Lossless: true})
+		plain := fixedTestTranslator(Options{Lossless: true})
+
+		pejelagarto := tagged.ToPejelagarto(input)
+		extracted, err := ExtractMetadata(pejelagarto)
+		if err != nil || !maps.Equal(extracted, metadata) {
+			t.Fatalf("ExtractMetadata() = %q, %v, want %q\nPejelagarto: %q", extracted, err, metadata, pejelagarto)
+		}
+		if got, want := plain.FromPejelagarto(pejelagarto), plain.FromPejelagarto(plain.ToPejelagarto(input)); got != want {
+			t.Errorf("text with metadata translates back differently\nGot:  %q\nWant: %q", got, want)
+		}
+		if verification := tagged.Verify(pejelagarto); verification != VerificationAuthentic {
+			t.Errorf("Verify() = %s, want authentic\nInput: %q", verification, input)
+		}
+	})
+}
+
+// FuzzDecodeMetadata tests that any bytes decode without panicking, and that decoded metadata encodes back to the same pairs
+func FuzzDecodeMetadata(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add(EncodeMetadata(Metadata{MetadataAuthor: "ana", "topic": "lizards"}))
+	f.Add([]byte{0x00, 0x05, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		metadata, err := DecodeMetadata(data)
+		if err != nil {
+			if !errors.Is(err, ErrDamagedMetadata) {
+				t.Errorf("DecodeMetadata() error %v does not wrap ErrDamagedMetadata", err)
+			}
+			return
+		}
+		if again, err := DecodeMetadata(EncodeMetadata(metadata)); err != nil || !maps.Equal(again, metadata) {
+			t.Errorf("re-encoded metadata decodes to %q, %v, want %q", again, err, metadata)
+		}
+	})
+}
+
+// TestMetadata verifies the encoding is compact and damaged or missing metadata is reported
+func TestMetadata(t *testing.T) {
+	metadata := Metadata{MetadataAuthor: "ana", MetadataMessage: "1001", MetadataSourceLanguage: "es", MetadataReplyTo: "998"}
+	// One byte per well-known key, two for each integer, the strings and the checksum
+	if encoded := EncodeMetadata(metadata); len(encoded) != 4+2+2+(1+3)+(1+2)+1 {
+		t.Errorf("EncodeMetadata() = %d bytes, want 15: % x", len(encoded), encoded)
+	}
+	if EncodeMetadata(nil) != nil {
+		t.Errorf("EncodeMetadata(nil) is not empty")
+	}
+
+	tr := fixedTestTranslator(Options{Rand: SeededRand(5), Key: []byte("key"), Metadata: metadata})
+	pejelagarto := tr.ToPejelagarto("see you at the pond")
+	if diagnostics := tr.Inspect(pejelagarto); len(diagnostics) > 0 {
+		t.Errorf("Inspect reported %+v", diagnostics)
+	}
+
+	digit := func(text string) string {
+		for _, r := range text {
+			if strings.Contains(strings.Join(MetadataDigitIndex, ""), string(r)) {
+				return string(r)
+			}
+		}
+		t.Fatalf("no metadata digit in %q", text)
+		return ""
+	}
+	other := MetadataDigitIndex[0]
+	if digit(pejelagarto) == other {
+		other = MetadataDigitIndex[1]
+	}
+	tests := []struct {
+		name     string
+		input    string
+		err      error
+		tampered bool // the signature covers the digits, not the markers
+	}{
+		{"untagged", TranslateToPejelagarto("see you at the pond"), ErrNoMetadata, false},
+		{"altered digit", strings.Replace(pejelagarto, digit(pejelagarto), other, 1), ErrDamagedMetadata, true},
+		{"missing digit", strings.Replace(pejelagarto, digit(pejelagarto), "", 1), ErrDamagedMetadata, true},
+		{"second marker", pejelagarto + MetadataMarker, ErrDamagedMetadata, false},
+		{"digit before the marker", MetadataDigitIndex[0] + pejelagarto, ErrDamagedMetadata, true},
+	}
+	for _, tt := range tests {
+		if _, err := ExtractMetadata(tt.input); !errors.Is(err, tt.err) {
+			t.Errorf("%s: ExtractMetadata() error = %v, want %v", tt.name, err, tt.err)
+		}
+		if tt.tampered {
+			if verification := tr.Verify(tt.input); verification != VerificationTampered {
+				t.Errorf("%s: Verify() = %s, want tampered", tt.name, verification)
+			}
+		}
+		if tt.err == ErrDamagedMetadata {
+			if diagnostics := tr.Inspect(tt.input); !slices.ContainsFunc(diagnostics, func(d Diagnostic) bool { return d.Code == DiagnosticMetadata }) {
+				t.Errorf("%s: Inspect did not report %s", tt.name, DiagnosticMetadata)
+			}
+		}
+	}
+
+	// Disabling the timestamp stage hides nothing
+	untimed := New(Options{DisableTimestamp: true, Metadata: metadata})
+	if _, err := ExtractMetadata(untimed.ToPejelagarto("see you at the pond")); !errors.Is(err, ErrNoMetadata) {
+		t.Errorf("ExtractMetadata() with DisableTimestamp error = %v, want ErrNoMetadata", err)
+	}
+}
@@ -0,0 +1,62 @@
+package translator
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalization
+// The accent wheels and the maps hold precomposed characters, so the encoder works on NFC text:
+// "é" typed as 'e' + U+0301 (NFD, e.g. from macOS) translates exactly like the precomposed "é"
+// Text in NFD is composed before the stages and NormalizationMarker is hidden with the timestamp
+// characters, so the decoder decomposes its output again and the round-trip stays exact
+// Text in neither form (mixed) is translated as it is, since composing it could not be undone
+// Normalization is a stage of its own, turned off only by DisableNormalization. With DisableTimestamp
+// the marker cannot be hidden, so it is written at the end of the text instead, and the markers of
+// the Human text are doubled: only a run of an odd number of markers ends with the stage's own
+
+// NormalizationMarker records that the Human text was in NFD
+var NormalizationMarker = "⏤"
+
+// canonicalForm returns input in NFC and whether it was in NFD
+// Text already in NFC, including plain ASCII, and mixed text are returned unchanged
+func canonicalForm(input string) (string, bool) {
+	if norm.NFC.IsNormalString(input) || !norm.NFD.IsNormalString(input) {
+		return input, false
+	}
+	return norm.NFC.String(input), true
+}
+
+// originalForm returns the output of decode in the form canonicalForm recorded
+func originalForm(human string, decomposed bool) string {
+	if !decomposed {
+		return human
+	}
+	return norm.NFD.String(human)
+}
+
+// doubleNormalizationMarkers doubles the markers of Human text, see cutNormalizationMarker
+func doubleNormalizationMarkers(input string) string {
+	return strings.ReplaceAll(input, NormalizationMarker, NormalizationMarker+NormalizationMarker)
+}
+
+// undoubleNormalizationMarkers reverses doubleNormalizationMarkers
+func undoubleNormalizationMarkers(input string) string {
+	return strings.ReplaceAll(input, NormalizationMarker+NormalizationMarker, NormalizationMarker)
+}
+
+// cutNormalizationMarker removes the marker written at the end of a text translated without the
+// timestamp stage, reporting whether the text was in NFD
+func cutNormalizationMarker(input string) (string, bool) {
+	trimmed := strings.TrimRight(input, NormalizationMarker)
+	if (len(input)-len(trimmed))/len(NormalizationMarker)%2 == 0 {
+		return input, false
+	}
+	return strings.TrimSuffix(input, NormalizationMarker), true
+}
+
+// isDecomposed reports whether Pejelagarto text carries NormalizationMarker
+func isDecomposed(input string) bool {
+	return strings.Contains(input, NormalizationMarker)
+}
@@ -0,0 +1,82 @@
+package translator
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// FuzzNormalization tests that NFC and NFD input translate alike and both round-trip exactly
+func FuzzNormalization(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("café crème brûlée")
+	f.Add("Ångström naïve señor\nÜber 'quoted' 42")
+	f.Add("e\u0301 q\u0301 가")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+		tr := fixedTestTranslator(Options{})
+
+		untimed := New(Options{DisableTimestamp: true})
+		composed := tr.ToPejelagarto(norm.NFC.String(input))
+		for _, form := range []norm.Form{norm.NFC, norm.NFD} {
+			text := RemoveTimestampSpecialCharacters(form.String(input))
+			pejelagarto := tr.ToPejelagarto(text)
+			if RemoveTimestampSpecialCharacters(pejelagarto) != RemoveTimestampSpecialCharacters(composed) {
+				t.Errorf("%v input translates differently\nNFC: %q\n%v: %q", form, composed, form, pejelagarto)
+			}
+			checkRoundTrip(t, tr, text)
+			// Without the timestamp stage the marker is written in the text
+			checkRoundTrip(t, untimed, form.String(input))
+		}
+	})
+}
+
+// TestNormalization verifies decomposed accents are treated as the precomposed ones and restored
+func TestNormalization(t *testing.T) {
+	tr := fixedTestTranslator(Options{})
+
+	tests := []struct {
+		input      string
+		decomposed bool
+	}{
+		{"café", false},
+		{"cafe\u0301", true},
+		{"plain ASCII", false},
+		{"mixed cafe\u0301 and café", false}, // neither form, translated as it is
+	}
+	for _, tt := range tests {
+		pejelagarto := tr.ToPejelagarto(tt.input)
+		if got := isDecomposed(pejelagarto); got != tt.decomposed {
+			t.Errorf("ToPejelagarto(%q) = %q, marker %v, want %v", tt.input, pejelagarto, got, tt.decomposed)
+		}
+		if reversed, _ := removeISO8601timestamp(tr.FromPejelagarto(pejelagarto)); reversed != tt.input {
+			t.Errorf("FromPejelagarto(%q) = %q, want %q", pejelagarto, reversed, tt.input)
+		}
+	}
+
+	if nfc, nfd := tr.ToPejelagarto("café"), tr.ToPejelagarto("cafe\u0301"); RemoveTimestampSpecialCharacters(nfc) != RemoveTimestampSpecialCharacters(nfd) {
+		t.Errorf("NFC and NFD input translate differently\nNFC: %q\nNFD: %q", nfc, nfd)
+	}
+
+	// Without the timestamp stage the marker is written at the end of the text, Human markers doubled
+	untimed := New(Options{DisableTimestamp: true})
+	for _, input := range []string{"cafe\u0301", "café", "cafe\u0301 ⏤", "⏤", "⏤⏤ café", ""} {
+		pejelagarto := untimed.ToPejelagarto(input)
+		if composed := untimed.ToPejelagarto(norm.NFC.String(input)); norm.NFD.IsNormalString(input) && !norm.NFC.IsNormalString(input) &&
+			pejelagarto != composed+NormalizationMarker {
+			t.Errorf("ToPejelagarto(%q) = %q, want the NFC translation %q and a marker", input, pejelagarto, composed)
+		}
+		if reversed := untimed.FromPejelagarto(pejelagarto); reversed != input {
+			t.Errorf("round-trip without timestamp = %q, want %q (through %q)", reversed, input, pejelagarto)
+		}
+	}
+
+	// Without normalization the combining accent is translated as a separate mark
+	plain := New(Options{DisableNormalization: true, DisableTimestamp: true})
+	if reversed := plain.FromPejelagarto(plain.ToPejelagarto("cafe\u0301")); reversed != "cafe\u0301" {
+		t.Errorf("round-trip without normalization = %q", reversed)
+	}
+}
@@ -0,0 +1,419 @@
+package translator
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Number literals
+// A literal is an optional '-' sign, an integer part whose digit groups may be joined by single
+// separators (1,000 or 1 000 000), an optional fraction after a decimal point and an optional
+// exponent ('e' or 'E', an optional sign and digits). Digits are the decimal digits of any script,
+// but all the digits of a literal belong to the script of its first one
+//
+// The number stage converts every digit part and keeps everything else: separators stay at the
+// same position counted from the right of the integer part, so the converted text splits into the
+// same literals and converts back exactly
+//   - integer part and exponent: base 10 <-> base 8, or base 7 when negative, leading zeros kept
+//   - fraction: n decimal digits <-> the shortest length m whose base 8 (base 7) fractions are finer
+//     than those of n decimal digits, the value rounded to the nearest; m grows with n, so the
+//     reverse finds n again
+//
+// The punctuation stage escapes the punctuation of literals, so that 3.14 keeps its point
+
+// numberGroupSeparators join the digit groups of the integer part of a literal
+const numberGroupSeparators = ",_'\u00A0\u202F\u066C"
+
+// numberDecimalPoints start the fraction of a literal
+const numberDecimalPoints = ".\u066B"
+
+// numberLiteral is a literal split into its parts, digits held as values
+type numberLiteral struct {
+	zero           rune // zero of the script of the digits
+	negative       bool
+	integer        []int
+	separators     []numberSeparator
+	point          rune // 0 without fraction
+	fraction       []int
+	exponent       rune // 'e' or 'E', 0 without exponent
+	exponentSign   rune // '+', '-' or 0
+	exponentDigits []int
+}
+
+// numberSeparator is a group separator of the integer part and the number of digits to its right
+type numberSeparator struct {
+	r      rune
+	offset int
+}
+
+// digitZero returns the zero of the script of a decimal digit
+// Unicode encodes the decimal digits of every script as contiguous runs of whole 0-9 sequences
+func digitZero(r rune) (rune, bool) {
+	if r >= '0' && r <= '9' {
+		return '0', true
+	}
+	if r < 0x80 || !unicode.Is(unicode.Nd, r) {
+		return 0, false
+	}
+	start := r
+	for unicode.Is(unicode.Nd, start-1) {
+		start--
+	}
+	return r - (r-start)%10, true
+}
+
+// parseNumberLiteral parses the literal starting at runes[start], returning where it ends
+func parseNumberLiteral(runes []rune, start int) (lit numberLiteral, end int, ok bool) {
+	i := start
+	if runes[i] == '-' {
+		lit.negative = true
+		i++
+	}
+	if i >= len(runes) {
+		return lit, 0, false
+	}
+	zero, isDigit := digitZero(runes[i])
+	if !isDigit {
+		return lit, 0, false
+	}
+	lit.zero = zero
+
+	digitAt := func(j int) bool {
+		if j >= len(runes) {
+			return false
+		}
+		z, isDigit := digitZero(runes[j])
+		return isDigit && z == zero
+	}
+	digits := func() []int {
+		var values []int
+		for digitAt(i) {
+			values = append(values, int(runes[i]-zero))
+			i++
+		}
+		return values
+	}
+
+	lit.integer = digits()
+	var separatorPositions []int
+	for i < len(runes) && strings.ContainsRune(numberGroupSeparators, runes[i]) && digitAt(i+1) {
+		lit.separators = append(lit.separators, numberSeparator{r: runes[i]})
+		separatorPositions = append(separatorPositions, len(lit.integer))
+		i++
+		lit.integer = append(lit.integer, digits()...)
+	}
+	for k, position := range separatorPositions {
+		lit.separators[k].offset = len(lit.integer) - position
+	}
+
+	if i < len(runes) && strings.ContainsRune(numberDecimalPoints, runes[i]) && digitAt(i+1) {
+		lit.point = runes[i]
+		i++
+		lit.fraction = digits()
+	}
+
+	if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+		j := i + 1
+		var sign rune
+		if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+			sign = runes[j]
+			j++
+		}
+		if digitAt(j) {
+			lit.exponent, lit.exponentSign = runes[i], sign
+			i = j
+			lit.exponentDigits = digits()
+		}
+	}
+	return lit, i, true
+}
+
+// numberLiteralPunctuation returns the positions of the runes of runes that are punctuation of a literal:
+// signs, separators and decimal points
+func numberLiteralPunctuation(runes []rune) map[int]bool {
+	var positions map[int]bool
+	for i := 0; i < len(runes); {
+		if _, isDigit := digitZero(runes[i]); !isDigit && runes[i] != '-' {
+			i++
+			continue
+		}
+		_, end, ok := parseNumberLiteral(runes, i)
+		if !ok {
+			i++
+			continue
+		}
+		for j := i; j < end; j++ {
+			if _, isDigit := digitZero(runes[j]); !isDigit && !unicode.IsLetter(runes[j]) {
+				if positions == nil {
+					positions = make(map[int]bool)
+				}
+				positions[j] = true
+			}
+		}
+		i = end
+	}
+	return positions
+}
+
+// isNumberPunctuation reports whether r can be the punctuation of a literal
+func isNumberPunctuation(r rune) bool {
+	return r == '-' || r == '+' || strings.ContainsRune(numberGroupSeparators, r) || strings.ContainsRune(numberDecimalPoints, r)
+}
+
+// convertNumbers converts every literal of input, leaving literals that cannot be converted as they are
+func convertNumbers(input string, toPejelagarto bool) string {
+	var result strings.Builder
+	result.Grow(len(input))
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		if _, isDigit := digitZero(runes[i]); !isDigit && runes[i] != '-' {
+			result.WriteRune(runes[i])
+			i++
+			continue
+		}
+		lit, end, ok := parseNumberLiteral(runes, i)
+		if !ok {
+			result.WriteRune(runes[i])
+			i++
+			continue
+		}
+		if converted, ok := lit.convert(toPejelagarto); ok {
+			result.WriteString(converted)
+		} else {
+			// Not a literal this direction can produce (e.g. digits 8 and 9 read as base 8), keep it
+			result.WriteString(string(runes[i:end]))
+		}
+		i = end
+	}
+	return result.String()
+}
+
+// legacyNumbersFromPejelagarto reads the numbers of texts written before number literals (see
+// Translator.legacyText): runs of ASCII digits in base 8, or base 7 after '-', leading zeros kept
+// A run with a digit its base does not have was written as it was
+func legacyNumbersFromPejelagarto(input string) string {
+	if !utf8.ValidString(input) {
+		return input
+	}
+	var result strings.Builder
+	result.Grow(len(input))
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		negative := runes[i] == '-' && i+1 < len(runes) && runes[i+1] >= '0' && runes[i+1] <= '6'
+		if !negative && (runes[i] < '0' || runes[i] > '7') {
+			result.WriteRune(runes[i])
+			i++
+			continue
+		}
+		base := 8
+		if negative {
+			base = 7
+			result.WriteRune('-')
+			i++
+		}
+		for i < len(runes) && runes[i] == '0' {
+			result.WriteRune('0')
+			i++
+		}
+		start := i
+		for i < len(runes) && runes[i] >= '0' && runes[i] < '0'+rune(base) {
+			i++
+		}
+		if i < len(runes) && runes[i] >= '0'+rune(base) && runes[i] <= '9' {
+			for i < len(runes) && runes[i] >= '0' && runes[i] <= '9' {
+				i++
+			}
+			result.WriteString(string(runes[start:i]))
+			continue
+		}
+		if start < i {
+			value, _ := new(big.Int).SetString(string(runes[start:i]), base)
+			result.WriteString(value.Text(10))
+		}
+	}
+	return result.String()
+}
+
+// convert renders the literal converted in the given direction
+func (lit numberLiteral) convert(toPejelagarto bool) (string, bool) {
+	pejelagartoBase := 8
+	if lit.negative {
+		pejelagartoBase = 7
+	}
+	from, to := 10, pejelagartoBase
+	if !toPejelagarto {
+		from, to = pejelagartoBase, 10
+	}
+
+	integer, ok := convertInteger(lit.integer, from, to)
+	if !ok {
+		return "", false
+	}
+	var result strings.Builder
+	if lit.negative {
+		result.WriteRune('-')
+	}
+	next := 0
+	for _, separator := range lit.separators {
+		at := len(integer) - separator.offset
+		if at <= next {
+			return "", false
+		}
+		lit.writeDigits(&result, integer[next:at])
+		result.WriteRune(separator.r)
+		next = at
+	}
+	lit.writeDigits(&result, integer[next:])
+
+	if lit.point != 0 {
+		fraction, ok := convertFraction(lit.fraction, pejelagartoBase, toPejelagarto)
+		if !ok {
+			return "", false
+		}
+		result.WriteRune(lit.point)
+		lit.writeDigits(&result, fraction)
+	}
+
+	if lit.exponent != 0 {
+		exponentBase := 8
+		if lit.exponentSign == '-' {
+			exponentBase = 7
+		}
+		from, to := 10, exponentBase
+		if !toPejelagarto {
+			from, to = exponentBase, 10
+		}
+		exponent, ok := convertInteger(lit.exponentDigits, from, to)
+		if !ok {
+			return "", false
+		}
+		result.WriteRune(lit.exponent)
+		if lit.exponentSign != 0 {
+			result.WriteRune(lit.exponentSign)
+		}
+		lit.writeDigits(&result, exponent)
+	}
+	return result.String(), true
+}
+
+// writeDigits writes digit values in the script of the literal
+func (lit numberLiteral) writeDigits(result *strings.Builder, digits []int) {
+	for _, digit := range digits {
+		result.WriteRune(lit.zero + rune(digit))
+	}
+}
+
+// convertInteger converts integer digits between bases, keeping leading zeros
+func convertInteger(digits []int, from, to int) ([]int, bool) {
+	leadingZeros := 0
+	for leadingZeros < len(digits) && digits[leadingZeros] == 0 {
+		leadingZeros++
+	}
+	value, ok := digitsValue(digits[leadingZeros:], from)
+	if !ok {
+		return nil, false
+	}
+	converted := make([]int, leadingZeros, len(digits)+len(digits)/2)
+	if leadingZeros < len(digits) {
+		converted = append(converted, valueDigits(value, to, 0)...)
+	}
+	return converted, true
+}
+
+// convertFraction converts fraction digits between base 10 and base (8 or 7), see Number literals
+func convertFraction(digits []int, base int, toPejelagarto bool) ([]int, bool) {
+	if toPejelagarto {
+		value, ok := digitsValue(digits, 10)
+		if !ok {
+			return nil, false
+		}
+		length := fractionLength(len(digits), base)
+		return valueDigits(roundedRatio(value, pow(base, length), pow(10, len(digits))), base, length), true
+	}
+
+	decimals, ok := decimalFractionLength(len(digits), base)
+	if !ok {
+		return nil, false
+	}
+	value, ok := digitsValue(digits, base)
+	if !ok {
+		return nil, false
+	}
+	decimal := roundedRatio(value, pow(10, decimals), pow(base, len(digits)))
+	if decimal.Cmp(pow(10, decimals)) >= 0 {
+		return nil, false
+	}
+	return valueDigits(decimal, 10, decimals), true
+}
+
+// fractionLength returns the number of base digits hiding n decimal digits: the smallest m with base^m > 10^n
+func fractionLength(n int, base int) int {
+	m := int(float64(n)*math.Log(10)/math.Log(float64(base))) + 1
+	decimals := pow(10, n)
+	for m > 1 && pow(base, m-1).Cmp(decimals) > 0 {
+		m--
+	}
+	for pow(base, m).Cmp(decimals) <= 0 {
+		m++
+	}
+	return m
+}
+
+// decimalFractionLength returns n such that fractionLength(n, base) is m, if there is one
+func decimalFractionLength(m int, base int) (int, bool) {
+	estimate := int(float64(m) * math.Log(float64(base)) / math.Log(10))
+	for n := max(estimate-2, 1); n <= estimate+2; n++ {
+		if fractionLength(n, base) == m {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// digitsValue returns the value of digits in base, false if a digit is not a digit of base
+func digitsValue(digits []int, base int) (*big.Int, bool) {
+	text := make([]byte, len(digits))
+	for i, digit := range digits {
+		if digit >= base {
+			return nil, false
+		}
+		text[i] = byte('0' + digit)
+	}
+	value := new(big.Int)
+	if len(text) > 0 {
+		value.SetString(string(text), base)
+	}
+	return value, true
+}
+
+// valueDigits returns the digits of value in base, left-padded with zeros to at least width digits
+func valueDigits(value *big.Int, base int, width int) []int {
+	text := value.Text(base)
+	if value.Sign() == 0 && width > 0 {
+		text = ""
+	}
+	digits := make([]int, 0, max(width, len(text)))
+	for i := len(text); i < width; i++ {
+		digits = append(digits, 0)
+	}
+	for _, c := range text {
+		digits = append(digits, int(c-'0'))
+	}
+	return digits
+}
+
+// roundedRatio returns value * numerator / denominator rounded to the nearest integer, halves up
+func roundedRatio(value, numerator, denominator *big.Int) *big.Int {
+	scaled := new(big.Int).Mul(value, numerator)
+	scaled.Lsh(scaled, 1)
+	scaled.Add(scaled, denominator)
+	return scaled.Quo(scaled, new(big.Int).Lsh(denominator, 1))
+}
+
+// pow returns base^exponent
+func pow(base, exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(int64(base)), big.NewInt(int64(exponent)), nil)
+}
@@ -0,0 +1,74 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNumberLiterals verifies numbers are converted as whole literals and converted back
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"integer", "1000", "1750"},
+		{"negative", "-8", "-11"},
+		{"leading zeros", "007", "007"},
+		{"fraction", "3.14", "3.110"},
+		{"trailing zero", "3.10", "3.063"},
+		{"negative fraction", "-2.5", "-2.34"},
+		{"thousands", "$1,299.99", "$2,423.773"},
+		{"other separators", "1\u202F000_000", "3\u202F641_100"},
+		{"space is not a separator", "1 010", "1 012"},
+		{"exponent", "6.02E+23", "6.012E+27"},
+		{"negative exponent", "1e-9", "1e-12"},
+		{"arabic-indic", "١٢٣٫٤٥", "١٧٣٫٣٤٦"},
+		{"full-width", "４２", "５２"},
+		{"mixed scripts", "1٢", "1٢"},
+		{"version", "1.2.3", "1.15.3"},
+		{"not a fraction", "3. Then", "3. Then"},
+	}
+	for _, tt := range tests {
+		got := applyNumbersLogicToPejelagarto(tt.input)
+		if got != tt.want {
+			t.Errorf("%s: applyNumbersLogicToPejelagarto(%q) = %q, want %q", tt.name, tt.input, got, tt.want)
+		}
+		if reversed := ApplyNumbersLogicFromPejelagarto(got); reversed != tt.input {
+			t.Errorf("%s: ApplyNumbersLogicFromPejelagarto(%q) = %q, want %q", tt.name, got, reversed, tt.input)
+		}
+	}
+
+	// The punctuation of a literal is escaped, so the punctuation stage does not double its point
+	if got := applyPunctuationReplacementsToPejelagarto("3.110 ends."); got != "3\u00AD.110 ends'.." {
+		t.Errorf("applyPunctuationReplacementsToPejelagarto() = %q, want the point of the number escaped", got)
+	}
+}
+
+// TestLegacyNumbers verifies texts written before number literals keep decoding their numbers
+func TestLegacyNumbers(t *testing.T) {
+	tests := []struct {
+		pejelagarto string
+		want        string
+	}{
+		{"173 -63 007 131 -141", "123 -45 007 89 -78"},
+		{"3.16", "3.14"},
+		{"2.5e12 1,000", "2.5e10 1,000"},
+		{"89 -78 -0", "89 -78 -0"},
+	}
+	for _, tt := range tests {
+		if got := legacyNumbersFromPejelagarto(tt.pejelagarto); got != tt.want {
+			t.Errorf("legacyNumbersFromPejelagarto(%q) = %q, want %q", tt.pejelagarto, got, tt.want)
+		}
+	}
+
+	// "3.14" as translated before number literals, its timestamp hidden but no ruleset version
+	baseline := "\u230F3\u00AD'..16\uA4FC\uFE71\u02B9\u2DFB"
+	if got, _ := removeISO8601timestamp(TranslateFromPejelagarto(baseline)); got != "3.14" {
+		t.Errorf("TranslateFromPejelagarto(baseline 3.14) = %q, want %q", got, "3.14")
+	}
+	// With a version the number is a literal
+	if got := TranslateFromPejelagarto(TranslateToPejelagarto("3.14")); !strings.HasPrefix(got, "3.14\n") {
+		t.Errorf("TranslateFromPejelagarto(TranslateToPejelagarto(3.14)) = %q", got)
+	}
+}
@@ -0,0 +1,495 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures a Translator
+// The zero value reproduces the behavior of TranslateToPejelagarto and TranslateFromPejelagarto
+// Text translated with some stages disabled must be translated back with the same options
+type Options struct {
+	// Clock returns the time hidden in the output when the input has no ISO 8601 timestamp line
+	// nil uses time.Now
+	Clock func() time.Time
+
+	// Timestamp, when non-zero, is hidden in the output instead of the time reported by Clock
+	Timestamp time.Time
+
+	// Rand returns the random source used to place the timestamp characters of one translation
+	// It is called once per translation, so returning a freshly seeded source (see SeededRand)
+	// makes the same input always produce the same output
+	// nil seeds a new source from the current time
+	Rand func() rand.Source
+
+	// Stage toggles, all stages run by default
+	DisableNumbers         bool // base 10 <-> base 8/7 number conversion
+	DisablePunctuation     bool // PunctuationMap replacements
+	DisableMapReplacements bool // ConjunctionMap and LetterMap replacements
+	DisableAccents         bool // prime factorization accent wheel
+	DisableConsonants      bool // base 3 digits consonant wheel
+	DisableCase            bool // Fibonacci/Tribonacci case inversion
+	DisableNormalization   bool // NFC canonical form of NFD input, see Unicode normalization
+
+	// Stages names the custom stages added with RegisterStage that run in this translator, in this
+	// order when they share a position; their names are hidden with the ruleset version
+	Stages []string
+
+	// Locality, when LocalitySentence or LocalityParagraph, runs the accent, consonant and case stages
+	// on every sentence or paragraph on its own, so that small edits cause small output changes
+	// The decoder reads it from the hidden marker, or from its own options with DisableTimestamp
+	Locality Locality
+
+	// DisableTimestamp skips the hidden timestamp in both directions:
+	// no special characters are removed or added and no timestamp line is extracted or appended
+	DisableTimestamp bool
+
+	// Lossless escapes the timestamp characters found in the Human text instead of removing them,
+	// so that they round-trip; the decoder reads it from the hidden marker
+	Lossless bool
+
+	// Metadata, when not empty, is hidden next to the timestamp characters (see EncodeMetadata);
+	// ExtractMetadata reads it back. It is part of the timestamp stage, like the signature
+	Metadata Metadata
+
+	// Ruleset, when set, is the dialect used instead of the current one (see GenerateRuleset)
+	// It must be valid and keep the current escape characters
+	Ruleset *Ruleset
+
+	// Glossary, when set, adds its pairs to the dialect ahead of every map entry
+	// It must have been built for Ruleset, or for the current dialect when Ruleset is nil;
+	// New takes a snapshot, pairs added later need a new Translator
+	Glossary *Glossary
+
+	// ProtectOpen and ProtectClose, when both set, delimit protected spans instead of the package defaults
+	// Text between them is never translated; neither may contain characters that a stage changes
+	ProtectOpen  string
+	ProtectClose string
+
+	// Key, when set, signs the output with an HMAC of the Human text and the hidden timestamp,
+	// hidden next to the timestamp characters; Verify checks it with the same key
+	// The signature is part of the timestamp stage, so DisableTimestamp also disables it
+	Key []byte
+}
+
+// ParseOptions builds Options from string settings such as URL query parameters
+// Stage toggles are booleans named numbers, punctuation, replacements, accents, consonants, case,
+// normalization and timestamp
+// (e.g. accents=false); timestamp also accepts an RFC 3339 time to embed, seed an integer
+// that makes the placement of the timestamp characters reproducible, and dialect a passphrase
+// selecting the private dialect built by GenerateRuleset; locality is text, sentence or paragraph,
+// lossless a boolean, metadata a JSON object of strings and stages the comma-separated names of
+// registered custom stages
+// The passphrase is a secret: callers should not take it from a URL, which ends up in logs
+func ParseOptions(values url.Values) (Options, error) {
+	var opts Options
+
+	toggles := []struct {
+		name    string
+		disable *bool
+	}{
+		{"numbers", &opts.DisableNumbers},
+		{"punctuation", &opts.DisablePunctuation},
+		{"replacements", &opts.DisableMapReplacements},
+		{"accents", &opts.DisableAccents},
+		{"consonants", &opts.DisableConsonants},
+		{"case", &opts.DisableCase},
+		{"normalization", &opts.DisableNormalization},
+	}
+	for _, toggle := range toggles {
+		value := values.Get(toggle.name)
+		if value == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return Options{}, fmt.Errorf("option %s: expected a boolean, got %q", toggle.name, value)
+		}
+		*toggle.disable = !enabled
+	}
+
+	if value := values.Get("timestamp"); value != "" {
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			opts.DisableTimestamp = !enabled
+		} else if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			opts.Timestamp = parsed
+		} else {
+			return Options{}, fmt.Errorf("option timestamp: expected a boolean or an RFC 3339 time, got %q", value)
+		}
+	}
+
+	if value := values.Get("seed"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return Options{}, fmt.Errorf("option seed: expected an integer, got %q", value)
+		}
+		opts.Rand = SeededRand(seed)
+	}
+
+	switch locality := Locality(values.Get("locality")); locality {
+	case "", "text":
+	case LocalitySentence, LocalityParagraph:
+		opts.Locality = locality
+	default:
+		return Options{}, fmt.Errorf("option locality: expected text, sentence or paragraph, got %q", locality)
+	}
+
+	if value := values.Get("lossless"); value != "" {
+		lossless, err := strconv.ParseBool(value)
+		if err != nil {
+			return Options{}, fmt.Errorf("option lossless: expected a boolean, got %q", value)
+		}
+		opts.Lossless = lossless
+	}
+
+	if value := values.Get("metadata"); value != "" {
+		if err := json.Unmarshal([]byte(value), &opts.Metadata); err != nil {
+			return Options{}, fmt.Errorf("option metadata: expected a JSON object of strings, got %q", value)
+		}
+		if size := len(EncodeMetadata(opts.Metadata)); size > MaxMetadataOptionBytes {
+			return Options{}, fmt.Errorf("option metadata: %d bytes once encoded, at most %d are accepted", size, MaxMetadataOptionBytes)
+		}
+	}
+
+	if value := values.Get("stages"); value != "" {
+		opts.Stages = strings.Split(value, ",")
+	}
+
+	if value := values.Get("dialect"); value != "" {
+		rs, err := GenerateRuleset(value)
+		if err != nil {
+			return Options{}, fmt.Errorf("option dialect: %w", err)
+		}
+		opts.Ruleset = rs
+	}
+
+	return opts, nil
+}
+
+// Translator translates between Human and Pejelagarto with fixed options
+// A Translator is immutable and safe for concurrent use, provided Options.Clock and Options.Rand are
+// A translator whose dialect, stages or protected span delimiters are invalid translates nothing: the methods returning an error return
+// the one of Err, the others an empty result
+type Translator struct {
+	opts     Options
+	compiled *compiledRules // compiled Options.Ruleset and Options.Glossary, nil for the current dialect
+	stages   []placedStage  // the registered stages of Options.Stages
+	err      error          // why the options cannot be used
+}
+
+// defaultTranslator backs the package-level translation functions
+var defaultTranslator = New(Options{})
+
+// New returns a Translator using the given options
+func New(opts Options) *Translator {
+	t := &Translator{opts: opts}
+	t.stages, t.err = lookupStages(opts.Stages)
+	switch {
+	case t.err != nil:
+	case opts.Glossary != nil:
+		rs := opts.Ruleset
+		if rs == nil {
+			rs = CurrentRuleset()
+		}
+		t.compiled, t.err = compileRulesetWithGlossary(rs.Clone(), opts.Glossary.Entries())
+	case opts.Ruleset != nil:
+		t.compiled, t.err = compileRuleset(opts.Ruleset.Clone())
+	}
+	if t.err == nil && opts.ProtectOpen != "" && opts.ProtectClose != "" {
+		rs := opts.Ruleset
+		if rs == nil {
+			rs = CurrentRuleset()
+		}
+		t.err = checkProtectDelimiters(opts, rs, t.stages)
+	}
+	return t
+}
+
+// Err returns why the translator cannot translate, nil when it can: a *RulesetError for an invalid
+// Options.Ruleset, or for current maps edited into an invalid dialect, or the reason custom
+// protected span delimiters were refused
+func (t *Translator) Err() error {
+	_, err := t.rules()
+	return err
+}
+
+// FixedClock returns a clock that always reports t, for reproducible timestamps
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+// SeededRand returns a random source factory that yields the same sequence on every translation
+func SeededRand(seed int64) func() rand.Source {
+	return func() rand.Source { return rand.NewSource(seed) }
+}
+
+// clock returns the configured timestamp, clock or time.Now
+func (t *Translator) clock() func() time.Time {
+	if !t.opts.Timestamp.IsZero() {
+		return FixedClock(t.opts.Timestamp)
+	}
+	if t.opts.Clock != nil {
+		return t.opts.Clock
+	}
+	return time.Now
+}
+
+// rules returns the compiled dialect of the translator
+func (t *Translator) rules() (*compiledRules, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.compiled != nil {
+		return t.compiled, nil
+	}
+	return currentCompiledRules()
+}
+
+// rng returns a new random generator for a single translation
+func (t *Translator) rng() *rand.Rand {
+	if t.opts.Rand != nil {
+		return rand.New(t.opts.Rand())
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// pipelineStep is one optional stage of the translation pipeline
+type pipelineStep struct {
+	name     string // the position of custom stages it stands for, see withStages
+	disabled bool
+	apply    func(string) string
+	// applyAligned, when set, applies the stage and aligns its output with its input
+	// Stages without it are aligned by diffing their input and output
+	applyAligned func(string) (string, alignment)
+}
+
+// runSteps applies the enabled steps in order, stopping early if the context is cancelled
+// When cuts is not nil, the alignment of every step is composed into it
+func runSteps(ctx context.Context, input string, steps []pipelineStep, cuts *alignment) (string, error) {
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if step.disabled {
+			continue
+		}
+		if cuts == nil {
+			input = step.apply(input)
+			continue
+		}
+
+		var output string
+		var stepCuts alignment
+		if step.applyAligned != nil {
+			output, stepCuts = step.applyAligned(input)
+		} else {
+			output = step.apply(input)
+			stepCuts = diffAlignment(input, output)
+		}
+		*cuts = composeAlignments(*cuts, stepCuts)
+		input = output
+	}
+	return input, ctx.Err()
+}
+
+// ToPejelagarto translates Human text to Pejelagarto
+func (t *Translator) ToPejelagarto(input string) string {
+	result, _ := t.ToPejelagartoContext(context.Background(), input)
+	return result
+}
+
+// FromPejelagarto translates Pejelagarto text back to Human
+func (t *Translator) FromPejelagarto(input string) string {
+	result, _ := t.FromPejelagartoContext(context.Background(), input)
+	return result
+}
+
+// ToPejelagartoContext translates Human text to Pejelagarto, returning ctx.Err() if cancelled between stages
+func (t *Translator) ToPejelagartoContext(ctx context.Context, input string) (string, error) {
+	return t.toPejelagarto(ctx, input, nil)
+}
+
+// toPejelagarto runs the stages of ToPejelagartoContext, composing their alignment into cuts when it is not nil
+func (t *Translator) toPejelagarto(ctx context.Context, input string, cuts *alignment) (string, error) {
+	var timestamp, human string
+	var decomposed, escaped bool
+	rules, err := t.rules()
+	if err != nil {
+		return "", err
+	}
+	metadata := EncodeMetadata(t.opts.Metadata)
+	protected := t.protectedSpans()
+	terminators := rules.sentenceTerminators()
+	local := func(stage func(string) string) func(string) string {
+		return func(input string) string { return applyLocally(input, stage, t.opts.Locality, terminators) }
+	}
+	result, err := runSteps(ctx, input, withStages([]pipelineStep{
+		{apply: sanitizeInvalidUTF8},
+		{disabled: t.opts.DisableTimestamp, apply: func(input string) string {
+			if t.opts.Lossless {
+				input, escaped = escapeTimestampSpecialCharacters(input)
+			} else {
+				input = RemoveTimestampSpecialCharacters(input)
+			}
+			input, timestamp = removeISO8601timestamp(input)
+			if timestamp == "" && !t.opts.Timestamp.IsZero() {
+				// Keep the offset of an explicit timestamp, the clock is always hidden in UTC
+				timestamp = t.opts.Timestamp.Format(time.RFC3339)
+			}
+			return input
+		}},
+		{disabled: t.opts.DisableNormalization, apply: func(input string) string {
+			input, decomposed = canonicalForm(input)
+			if t.opts.DisableTimestamp {
+				input = doubleNormalizationMarkers(input)
+			}
+			return input
+		}},
+		{apply: func(input string) string {
+			// The signature covers the Human text as the decoder gives it back before finishDecode
+			human = input
+			return input
+		}},
+		{name: "protect", apply: protected.protect, applyAligned: protected.protectAligned},
+		{name: "numbers", disabled: t.opts.DisableNumbers, apply: applyNumbersLogicToPejelagarto},
+		{name: "punctuation", disabled: t.opts.DisablePunctuation, apply: rules.replacePunctuationToPejelagarto, applyAligned: rules.replacePunctuationToPejelagartoAligned},
+		{name: "replacements", disabled: t.opts.DisableMapReplacements, apply: rules.replaceMapsToPejelagarto, applyAligned: rules.replaceMapsToPejelagartoAligned},
+		{name: "accents", disabled: t.opts.DisableAccents, apply: local(rules.wheels.applyToPejelagarto)},
+		{name: "consonants", disabled: t.opts.DisableConsonants, apply: local(rules.consonants.applyToPejelagarto)},
+		{name: "case", disabled: t.opts.DisableCase, apply: local(applyCaseReplacementLogic)},
+		{disabled: t.opts.DisableTimestamp, apply: func(input string) string {
+			specialChars := timestampToEncode(timestamp, t.clock()).specialChars()
+			var markers []string
+			if decomposed {
+				markers = append(markers, NormalizationMarker)
+			}
+			if marker := t.opts.Locality.marker(); marker != "" {
+				markers = append(markers, marker)
+			}
+			if escaped {
+				markers = append(markers, LosslessMarker)
+			}
+			if t.compiled == nil {
+				markers = append(markers, rulesetVersionChars(rules.fingerprint, t.stageNames())...)
+			} else if len(t.stages) > 0 {
+				markers = append(markers, rulesetVersionChars(privateRulesetVersion, t.stageNames())...)
+			}
+			if len(metadata) > 0 {
+				markers = append(markers, metadataChars(metadata)...)
+			}
+			if len(t.opts.Key) > 0 {
+				specialChars = append(specialChars, signatureChars(t.opts.Key, human, specialChars, markers)...)
+			}
+			return insertSpecialChars(input, append(specialChars, markers...), t.rng())
+		}},
+		{name: "restore", apply: protected.restore, applyAligned: protected.restoreAligned},
+		{disabled: t.opts.DisableNormalization || !t.opts.DisableTimestamp, apply: func(input string) string {
+			if decomposed {
+				input += NormalizationMarker
+			}
+			return input
+		}},
+	}, t.stages, false), cuts)
+	if err == nil {
+		err = protected.err
+	}
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// FromPejelagartoContext translates Pejelagarto text back to Human, returning ctx.Err() if cancelled between stages
+func (t *Translator) FromPejelagartoContext(ctx context.Context, input string) (string, error) {
+	return t.fromPejelagarto(ctx, input, nil)
+}
+
+// fromPejelagarto runs the stages of FromPejelagartoContext, composing their alignment into cuts when it is not nil
+func (t *Translator) fromPejelagarto(ctx context.Context, input string, cuts *alignment) (string, error) {
+	decoded, err := t.decode(ctx, input, cuts)
+	if err != nil {
+		return "", err
+	}
+	result := t.finishDecode(decoded)
+	if cuts != nil {
+		*cuts = composeAlignments(*cuts, diffAlignment(decoded.human, result))
+	}
+	return result, nil
+}
+
+// decoded is the output of decode
+type decoded struct {
+	human      string // Human text in NFC, still without its timestamp line
+	timestamp  string // hidden timestamp, empty if none
+	decomposed bool   // the Human text was in NFD
+	lossless   bool   // the timestamp characters of the Human text are escaped
+}
+
+// decode reverses the stages
+func (t *Translator) decode(ctx context.Context, input string, cuts *alignment) (decoded, error) {
+	var d decoded
+	rules, err := t.decodingRules(input)
+	if err != nil {
+		return d, err
+	}
+	if err := t.checkTextStages(input); err != nil {
+		return d, err
+	}
+	protected := t.protectedSpans()
+	terminators := rules.sentenceTerminators()
+	locality := t.locality(input)
+	local := func(stage func(string) string) func(string) string {
+		return func(input string) string { return applyLocally(input, stage, locality, terminators) }
+	}
+	legacy := t.legacyText(input)
+	accents, numbers := rules.wheels.applyFromPejelagarto, ApplyNumbersLogicFromPejelagarto
+	if legacy {
+		accents, numbers = rules.wheels.legacyApplyFromPejelagarto, legacyNumbersFromPejelagarto
+	}
+	human, err := runSteps(ctx, input, withStages([]pipelineStep{
+		{disabled: t.opts.DisableTimestamp, apply: func(input string) string {
+			hidden, rest, lossless := splitHiddenTimestampChars(input)
+			d.timestamp = readTimestampUsingSpecialCharEncoding(hidden)
+			d.decomposed = !t.opts.DisableNormalization && isDecomposed(hidden)
+			d.lossless = lossless
+			return rest
+		}},
+		{disabled: t.opts.DisableNormalization || !t.opts.DisableTimestamp, apply: func(input string) string {
+			input, d.decomposed = cutNormalizationMarker(input)
+			return input
+		}},
+		{name: "protect", apply: protected.protect, applyAligned: protected.protectAligned},
+		{name: "case", disabled: t.opts.DisableCase, apply: local(applyCaseReplacementLogic)},
+		{name: "consonants", disabled: t.opts.DisableConsonants || legacy, apply: local(rules.consonants.applyFromPejelagarto)},
+		{name: "accents", disabled: t.opts.DisableAccents, apply: local(accents)},
+		{name: "replacements", disabled: t.opts.DisableMapReplacements, apply: rules.replaceMapsFromPejelagarto, applyAligned: rules.replaceMapsFromPejelagartoAligned},
+		{name: "punctuation", disabled: t.opts.DisablePunctuation, apply: rules.replacePunctuationFromPejelagarto, applyAligned: rules.replacePunctuationFromPejelagartoAligned},
+		{name: "numbers", disabled: t.opts.DisableNumbers, apply: numbers},
+		{name: "restore", apply: protected.restore, applyAligned: protected.restoreAligned},
+		{disabled: t.opts.DisableNormalization || !t.opts.DisableTimestamp, apply: undoubleNormalizationMarkers},
+	}, t.stages, true), cuts)
+	if err == nil {
+		err = protected.err
+	}
+	if err != nil {
+		return decoded{}, err
+	}
+	d.human = human
+	return d, nil
+}
+
+// finishDecode restores the normalization form of the output of decode, unescapes its timestamp
+// characters, appends its timestamp line and restores invalid UTF-8
+func (t *Translator) finishDecode(d decoded) string {
+	human := originalForm(d.human, d.decomposed)
+	if d.lossless {
+		human = outputUnescape(human)
+	}
+	return unsanitizeInvalidUTF8(addISO8601timestamp(human, d.timestamp))
+}
@@ -0,0 +1,151 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TestDeterministicTranslation verifies a fixed clock and seeded source give byte-for-byte identical output
+func TestDeterministicTranslation(t *testing.T) {
+	first := fixedTestTranslator(Options{Clock: FixedClock(fixedTestTime), Rand: SeededRand(42)})
+	second := fixedTestTranslator(Options{Clock: FixedClock(fixedTestTime), Rand: SeededRand(42)})
+
+	input := "Hello World, the quick brown fox jumps over 13 lazy dogs."
+	expected := first.ToPejelagarto(input)
+	for i := 0; i < 5; i++ {
+		if got := first.ToPejelagarto(input); got != expected {
+			t.Fatalf("repeated translation differs:\nfirst: %q\nlater: %q", expected, got)
+		}
+		if got := second.ToPejelagarto(input); got != expected {
+			t.Fatalf("translation with equal options differs:\nfirst:  %q\nsecond: %q", expected, got)
+		}
+	}
+
+	reversed := first.FromPejelagarto(expected)
+	if want := input + "\n2026-03-14T15:09:26Z"; reversed != want {
+		t.Errorf("FromPejelagarto() = %q, want %q", reversed, want)
+	}
+}
+
+// FuzzTranslateWithoutTimestamp tests that disabling the timestamp makes the pipeline exactly reversible
+func FuzzTranslateWithoutTimestamp(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("")
+	f.Add("Hello, World! 123\n2025-10-19T14:30:45Z")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		tr := New(Options{DisableTimestamp: true})
+		pejelagarto := checkRoundTrip(t, tr, input)
+		if again := tr.ToPejelagarto(input); again != pejelagarto {
+			t.Fatalf("output not reproducible:\nfirst:  %q\nsecond: %q", pejelagarto, again)
+		}
+	})
+}
+
+// FuzzTranslateWithStageToggles tests that any combination of disabled stages still round-trips
+func FuzzTranslateWithStageToggles(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("", uint8(0))
+	f.Add("Hello, World! 123", uint8(0b10101))
+	f.Fuzz(func(t *testing.T, input string, toggles uint8) {
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		checkRoundTrip(t, New(Options{
+			DisableNumbers:         toggles&1 != 0,
+			DisablePunctuation:     toggles&2 != 0,
+			DisableMapReplacements: toggles&4 != 0,
+			DisableAccents:         toggles&8 != 0,
+			DisableCase:            toggles&16 != 0,
+			DisableTimestamp:       true,
+		}), input)
+	})
+}
+
+// TestParseOptions verifies query-style settings map onto Options
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions(url.Values{
+		"accents":   {"false"},
+		"case":      {"0"},
+		"numbers":   {"true"},
+		"timestamp": {"2025-10-19T14:30:00Z"},
+		"seed":      {"7"},
+	})
+	if err != nil {
+		t.Fatalf("ParseOptions() unexpected error: %v", err)
+	}
+	if !opts.DisableAccents || !opts.DisableCase || opts.DisableNumbers || opts.DisableTimestamp {
+		t.Errorf("stage toggles parsed incorrectly: %+v", opts)
+	}
+	if want := time.Date(2025, time.October, 19, 14, 30, 0, 0, time.UTC); !opts.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", opts.Timestamp, want)
+	}
+	if opts.Rand == nil {
+		t.Errorf("seed did not set Rand")
+	}
+
+	opts, err = ParseOptions(url.Values{"timestamp": {"false"}})
+	if err != nil || !opts.DisableTimestamp {
+		t.Errorf("timestamp=false: got %+v, %v", opts, err)
+	}
+
+	if _, err := ParseOptions(url.Values{"accents": {"maybe"}}); err == nil {
+		t.Errorf("expected an error for a non-boolean toggle")
+	}
+
+	opts, err = ParseOptions(url.Values{"locality": {"sentence"}})
+	if err != nil || opts.Locality != LocalitySentence {
+		t.Errorf("locality=sentence: got %+v, %v", opts, err)
+	}
+	if _, err := ParseOptions(url.Values{"locality": {"word"}}); err == nil {
+		t.Errorf("expected an error for an unknown locality")
+	}
+
+	opts, err = ParseOptions(url.Values{"dialect": {"our little secret"}})
+	if err != nil || opts.Ruleset == nil || opts.Ruleset.Fingerprint() != generatedRuleset(t, "our little secret").Fingerprint() || opts.Key != nil {
+		t.Errorf("ParseOptions(dialect) = %+v, %v, want the generated dialect and no signing key", opts, err)
+	}
+
+	opts, err = ParseOptions(url.Values{"stages": {"rot13,vowels"}})
+	if err != nil || len(opts.Stages) != 2 || opts.Stages[0] != "rot13" || opts.Stages[1] != "vowels" {
+		t.Errorf("stages=rot13,vowels: got %+v, %v", opts, err)
+	}
+
+	opts, err = ParseOptions(url.Values{"lossless": {"true"}})
+	if err != nil || !opts.Lossless {
+		t.Errorf("lossless=true: got %+v, %v", opts, err)
+	}
+
+	opts, err = ParseOptions(url.Values{"metadata": {`{"author":"ana","reply_to":"41"}`}})
+	if err != nil || opts.Metadata[MetadataAuthor] != "ana" || opts.Metadata[MetadataReplyTo] != "41" {
+		t.Errorf("metadata: got %+v, %v", opts, err)
+	}
+	for _, metadata := range []string{`["author"]`, `{"message":42}`, `{"long":"` + strings.Repeat("x", MaxMetadataOptionBytes) + `"}`} {
+		if _, err := ParseOptions(url.Values{"metadata": {metadata}}); err == nil {
+			t.Errorf("expected an error for metadata %.20s", metadata)
+		}
+	}
+}
+
+// TestTranslateContextCancelled verifies a cancelled context stops the translation
+func TestTranslateContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tr := New(Options{})
+	if _, err := tr.ToPejelagartoContext(ctx, "hello"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ToPejelagartoContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := tr.FromPejelagartoContext(ctx, "araka"); !errors.Is(err, context.Canceled) {
+		t.Errorf("FromPejelagartoContext() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,261 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Protected spans
+// Text between an opening and a closing delimiter (ProtectOpen and ProtectClose by default) passes
+// through every stage untouched, delimiters included, e.g. for product names, code or IDs
+// Before the stages each span is reduced to its bare delimiters, a placeholder that no stage changes,
+// so the rest of the text translates the same whatever the spans hold: the prime factors and the
+// Fibonacci/Tribonacci positions only see the delimiters. The contents are put back afterwards
+// A span ends at the first closing delimiter after its opening one; an opening delimiter with no
+// closing one after it is plain text, and so is a closing delimiter outside a span
+// Custom delimiters must survive every enabled stage, New refuses the others: a placeholder
+// that a stage rewrites is no longer found, and the translation then fails rather than drop the span
+
+// Default delimiters of protected spans
+var (
+	ProtectOpen  = "⟦"
+	ProtectClose = "⟧"
+)
+
+// protectedSpans holds the contents taken out of a text by protect, in order
+type protectedSpans struct {
+	open, close []rune
+	contents    [][]rune
+	err         error // why restore could not put every content back
+}
+
+// protectedSpans returns the empty span list for the translator's delimiters
+func (t *Translator) protectedSpans() *protectedSpans {
+	open, close := t.opts.ProtectOpen, t.opts.ProtectClose
+	if open == "" || close == "" {
+		open, close = ProtectOpen, ProtectClose
+	}
+	return &protectedSpans{open: []rune(open), close: []rune(close)}
+}
+
+// findProtectedSpans returns the [start, end) rune ranges of the contents of the protected spans of runes
+func findProtectedSpans(runes []rune, open, close []rune) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(runes); {
+		start := indexRunes(runes, open, i)
+		if start < 0 {
+			break
+		}
+		start += len(open)
+		end := indexRunes(runes, close, start)
+		if end < 0 {
+			break
+		}
+		spans = append(spans, [2]int{start, end})
+		i = end + len(close)
+	}
+	return spans
+}
+
+// indexRunes returns the index of the first sep in runes at or after from, or -1
+func indexRunes(runes []rune, sep []rune, from int) int {
+	for i := from; i+len(sep) <= len(runes); i++ {
+		if string(runes[i:i+len(sep)]) == string(sep) {
+			return i
+		}
+	}
+	return -1
+}
+
+// protect takes the contents of the protected spans out of input, leaving their delimiters
+func (p *protectedSpans) protect(input string) string {
+	result, _ := p.run(input, false)
+	return result
+}
+
+// protectAligned is protect aligning its output with its input
+func (p *protectedSpans) protectAligned(input string) (string, alignment) {
+	return p.run(input, true)
+}
+
+// run takes the contents out of input, recording the alignment only when aligned is set
+// Each content is aligned with the empty place between its delimiters, so that composed with
+// restoreAligned it is aligned with itself
+func (p *protectedSpans) run(input string, aligned bool) (string, alignment) {
+	p.contents, p.err = p.contents[:0], nil
+	runes := []rune(input)
+	spans := findProtectedSpans(runes, p.open, p.close)
+	if len(spans) == 0 {
+		return input, identityAlignment(len(runes))
+	}
+
+	var result strings.Builder
+	result.Grow(len(input))
+	var cuts alignment
+	if aligned {
+		cuts = alignment{{0, 0}}
+	}
+	source, target := 0, 0
+	for _, span := range spans {
+		result.WriteString(string(runes[source:span[0]]))
+		if aligned {
+			for source < span[0] {
+				source, target = source+1, target+1
+				cuts = append(cuts, alignCut{source, target})
+			}
+			cuts = appendCut(cuts, alignCut{span[1], target})
+		}
+		p.contents = append(p.contents, runes[span[0]:span[1]])
+		source = span[1]
+	}
+	result.WriteString(string(runes[source:]))
+	if aligned {
+		for source < len(runes) {
+			source, target = source+1, target+1
+			cuts = append(cuts, alignCut{source, target})
+		}
+	}
+	return result.String(), cuts
+}
+
+// restore puts the contents taken out by protect back between their delimiters
+func (p *protectedSpans) restore(input string) string {
+	result, _ := p.restoreRun(input, false)
+	return result
+}
+
+// restoreAligned is restore aligning its output with its input
+func (p *protectedSpans) restoreAligned(input string) (string, alignment) {
+	return p.restoreRun(input, true)
+}
+
+// restoreRun puts the contents back, recording the alignment only when aligned is set
+// A placeholder that is not found leaves the text as it is and sets p.err
+func (p *protectedSpans) restoreRun(input string, aligned bool) (string, alignment) {
+	if len(p.contents) == 0 {
+		return input, identityAlignment(utf8.RuneCountInString(input))
+	}
+
+	runes := []rune(input)
+	placeholder := append(append([]rune(nil), p.open...), p.close...)
+	var result strings.Builder
+	result.Grow(len(input))
+	var cuts alignment
+	if aligned {
+		cuts = alignment{{0, 0}}
+	}
+	source, target := 0, 0
+	for i, content := range p.contents {
+		at := indexRunes(runes, placeholder, source)
+		if at < 0 {
+			p.err = fmt.Errorf("protected span %d of %d: its delimiters %q were rewritten by a stage", i+1, len(p.contents), string(placeholder))
+			return input, identityAlignment(len(runes))
+		}
+		at += len(p.open)
+		result.WriteString(string(runes[source:at]))
+		result.WriteString(string(content))
+		if aligned {
+			for source < at {
+				source, target = source+1, target+1
+				cuts = append(cuts, alignCut{source, target})
+			}
+			cuts = appendCut(cuts, alignCut{at, target + len(content)})
+		}
+		source, target = at, target+len(content)
+	}
+	result.WriteString(string(runes[source:]))
+	if aligned {
+		for source < len(runes) {
+			source, target = source+1, target+1
+			cuts = append(cuts, alignCut{source, target})
+		}
+	}
+	return result.String(), cuts
+}
+
+// checkProtectDelimiters returns why the custom delimiters of opts cannot delimit protected spans in
+// the dialect rs, nil if they can
+// A delimiter may not hold whitespace or a rune that an enabled stage rewrites
+func checkProtectDelimiters(opts Options, rs *Ruleset, stages []placedStage) error {
+	// rewritten maps the runes of the maps and wheels to the first stage that rewrites them
+	rewritten := make(map[rune]string)
+	mark := func(stage string, texts ...string) {
+		for _, text := range texts {
+			for _, r := range text {
+				if _, ok := rewritten[r]; !ok {
+					rewritten[r] = stage
+				}
+			}
+		}
+	}
+	mark("replacements", string(rs.InternalEscapeChar), string(rs.OutputEscapeChar))
+	if !opts.DisableTimestamp {
+		for _, chars := range timestampSpecialCharTables() {
+			mark("timestamp", chars...)
+		}
+	}
+	if !opts.DisablePunctuation {
+		for key, value := range rs.PunctuationMap {
+			mark("punctuation", key, value)
+		}
+	}
+	if !opts.DisableMapReplacements {
+		maps := rs.replacementMaps()
+		if opts.Glossary != nil {
+			maps = append(maps, opts.Glossary.Entries())
+		}
+		for _, replacements := range maps {
+			for key, value := range replacements {
+				mark("replacements", key, value)
+			}
+		}
+	}
+	for _, wheels := range []struct {
+		stage    string
+		disabled bool
+		wheels   []map[rune][]string
+	}{
+		{"accents", opts.DisableAccents, []map[rune][]string{rs.OneRuneAccentsWheel, rs.TwoRunesAccentsWheel}},
+		{"consonants", opts.DisableConsonants, []map[rune][]string{rs.ConsonantWheel}},
+	} {
+		if wheels.disabled {
+			continue
+		}
+		for _, wheel := range wheels.wheels {
+			for base, forms := range wheel {
+				mark(wheels.stage, string(base))
+				mark(wheels.stage, forms...)
+			}
+		}
+	}
+
+	for _, delimiter := range []string{opts.ProtectOpen, opts.ProtectClose} {
+		if !opts.DisableNormalization && !norm.NFC.IsNormalString(delimiter) {
+			return fmt.Errorf("protected span delimiter %q: not in NFC, the normalization stage rewrites it", delimiter)
+		}
+		for _, r := range delimiter {
+			stage, ok := rewritten[r]
+			switch {
+			case unicode.IsSpace(r):
+				return fmt.Errorf("protected span delimiter %q: holds whitespace %q", delimiter, r)
+			case !opts.DisableNumbers && unicode.IsDigit(r):
+				stage, ok = "numbers", true
+			case !opts.DisableCase && (unicode.ToUpper(r) != r || unicode.ToLower(r) != r):
+				stage, ok = "case", true
+			}
+			if ok {
+				return fmt.Errorf("protected span delimiter %q: the %s stage rewrites %q", delimiter, stage, r)
+			}
+		}
+		for _, placed := range stages {
+			if placed.stage.Forward(delimiter) != delimiter {
+				return fmt.Errorf("protected span delimiter %q: the stage %q rewrites it", delimiter, placed.stage.Name())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzProtectedSpans tests that protected spans round-trip and come out of the translation unchanged
+func FuzzProtectedSpans(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Install ", "pejelagarto-v2.1", " with 42 cats")
+	f.Add("", "'quoted' ­ 123", "")
+	f.Add("stray ⟧ ", "⟦nested", " ⟦ open")
+	f.Fuzz(func(t *testing.T, before string, protected string, after string) {
+		// The first closing delimiter ends the span
+		protected, _, _ = strings.Cut(protected, ProtectClose)
+		input := before + ProtectOpen + protected + ProtectClose + after
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		checkRoundTrip(t, fixedTestTranslator(Options{}), input)
+		checkRoundTrip(t, New(Options{DisableTimestamp: true}), input)
+	})
+}
+
+// TestProtectedSpans verifies protected text is kept verbatim and does not change the rest of the translation
+func TestProtectedSpans(t *testing.T) {
+	tr := New(Options{DisableTimestamp: true})
+
+	tests := []struct {
+		input     string
+		protected []string
+	}{
+		{"Use ⟦pejelagarto-v2.1⟧ with 42 cats", []string{"⟦pejelagarto-v2.1⟧"}},
+		{"⟦ID 0042⟧ and ⟦Ünïcode 'quotes'⟧", []string{"⟦ID 0042⟧", "⟦Ünïcode 'quotes'⟧"}},
+		{"stray ⟧ and ⟦ open", nil},
+	}
+	for _, tt := range tests {
+		pejelagarto := tr.ToPejelagarto(tt.input)
+		for _, span := range tt.protected {
+			if !strings.Contains(pejelagarto, span) {
+				t.Errorf("ToPejelagarto(%q) = %q, want %q untouched", tt.input, pejelagarto, span)
+			}
+		}
+		if reversed := tr.FromPejelagarto(pejelagarto); reversed != tt.input {
+			t.Errorf("FromPejelagarto(%q) = %q, want %q", pejelagarto, reversed, tt.input)
+		}
+	}
+
+	// Only the delimiters of a span count for the accent and case positions of the rest
+	short := tr.ToPejelagarto("Use ⟦x⟧ with 42 cats")
+	long := tr.ToPejelagarto("Use ⟦a much longer protected span⟧ with 42 cats")
+	if want := strings.Replace(short, "⟦x⟧", "⟦a much longer protected span⟧", 1); long != want {
+		t.Errorf("the span changed the rest of the translation\nShort: %q\nLong:  %q", short, long)
+	}
+
+	custom := New(Options{DisableTimestamp: true, ProtectOpen: "{{", ProtectClose: "}}"})
+	if got := custom.ToPejelagarto("keep {{this}}"); !strings.HasSuffix(got, "{{this}}") {
+		t.Errorf("ToPejelagarto() with custom delimiters = %q, want the span untouched", got)
+	}
+}
+
+// TestProtectDelimiters verifies New refuses custom delimiters that a stage rewrites
+func TestProtectDelimiters(t *testing.T) {
+	tests := []struct {
+		opts  Options
+		valid bool
+	}{
+		{Options{ProtectOpen: "{{", ProtectClose: "}}"}, true},
+		{Options{ProtectOpen: "<<", ProtectClose: ">>"}, true},
+		{Options{ProtectOpen: "<< ", ProtectClose: ">>"}, false},
+		{Options{ProtectOpen: "<<", ProtectClose: ">>\n"}, false},
+		{Options{ProtectOpen: "[[", ProtectClose: "!!"}, false},
+		{Options{ProtectOpen: "[[", ProtectClose: "!!", DisablePunctuation: true}, true},
+		{Options{ProtectOpen: "#x", ProtectClose: "x#"}, false},
+		{Options{ProtectOpen: "#1", ProtectClose: "1#"}, false},
+		{Options{ProtectOpen: "#1", ProtectClose: "1#", DisableNumbers: true}, true},
+		{Options{ProtectOpen: "\\[", ProtectClose: "\\]"}, false},
+		{Options{ProtectOpen: "{" + DaySpecialCharIndex[0], ProtectClose: "}"}, false},
+		{Options{ProtectOpen: "{" + DaySpecialCharIndex[0], ProtectClose: "}", DisableTimestamp: true}, true},
+		{Options{ProtectOpen: "é{", ProtectClose: "}", DisableCase: true, DisableAccents: true, DisableMapReplacements: true}, false},
+	}
+	for _, tt := range tests {
+		err := New(tt.opts).Err()
+		if (err == nil) != tt.valid {
+			t.Errorf("New(%q, %q).Err() = %v, want valid %v", tt.opts.ProtectOpen, tt.opts.ProtectClose, err, tt.valid)
+		}
+	}
+}
+
+// TestRestoreLostPlaceholder verifies a span whose placeholder a stage rewrote fails the translation
+func TestRestoreLostPlaceholder(t *testing.T) {
+	p := New(Options{}).protectedSpans()
+	if got := p.protect("keep ⟦this⟧ and ⟦that⟧"); got != "keep ⟦⟧ and ⟦⟧" {
+		t.Fatalf("protect() = %q", got)
+	}
+	if got := p.restore("keep ⟦⟧ and ⟦⟧"); got != "keep ⟦this⟧ and ⟦that⟧" || p.err != nil {
+		t.Errorf("restore() = %q, %v", got, p.err)
+	}
+	if got := p.restore("keep ⟦⟧ and ⟦ ⟧"); got != "keep ⟦⟧ and ⟦ ⟧" || p.err == nil {
+		t.Errorf("restore() of a rewritten placeholder = %q, %v, want the text unchanged and an error", got, p.err)
+	}
+}
@@ -0,0 +1,296 @@
+package translator
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Recovery of hand-typed Pejelagarto
+// People retyping Pejelagarto leave out what they cannot type or see: the accents and consonant
+// diacritics, the hidden timestamp characters (and with them the locality marker), the soft hyphen
+// escapes and often the case pattern. FromPejelagarto then moves plain vowels and consonants back
+// along their wheels and inverts the wrong letters' case
+// The accent and consonant stages are deterministic: a Human text without diacritics always gets
+// the same ones. RecoverFromPejelagarto strips the diacritics of the typed text, puts back the ones
+// the encoder would have added, and decodes that canonical form. The locality and whether the typed
+// case is the translator's are unknown, so every reading is decoded and they are ranked: readings
+// that encode back to the typed text first, then those whose case looks like Human text
+// Diacritics of the Human text itself cannot be recovered
+
+// RecoveryCandidate is one reading of hand-typed Pejelagarto
+type RecoveryCandidate struct {
+	Text      string   `json:"text"`      // the Human text
+	Canonical string   `json:"canonical"` // the Pejelagarto text it was read as, without hidden characters
+	Locality  Locality `json:"locality"`
+	// TypedCase is true when the typed case was read as the case pattern of the translator,
+	// false when it was kept as the case of the Human text
+	TypedCase bool `json:"typed_case"`
+	// Consistent is true when Text translates back to the typed text, diacritics, escapes and
+	// hidden characters left out
+	Consistent bool `json:"consistent"`
+	// Penalty counts the words of Text whose case is unusual in Human text (e.g. "hELlo"), lower ranks first
+	Penalty int `json:"penalty"`
+}
+
+// RecoverFromPejelagarto reads Pejelagarto text retyped without its accents, hidden characters or
+// case pattern, returning the candidate readings best first
+func RecoverFromPejelagarto(input string) []RecoveryCandidate {
+	return defaultTranslator.RecoverFromPejelagarto(input)
+}
+
+// RecoverFromPejelagarto reads Pejelagarto text retyped without its accents, hidden characters or
+// case pattern with the translator's options, returning the candidate readings best first
+// Identical readings are listed once; a text that kept its hidden characters is first read as FromPejelagarto does
+// A translator with an invalid dialect (see Err) returns no candidates
+func (t *Translator) RecoverFromPejelagarto(input string) []RecoveryCandidate {
+	candidates, _ := t.RecoverFromPejelagartoContext(context.Background(), input)
+	return candidates
+}
+
+// RecoverFromPejelagartoContext reads hand-typed Pejelagarto as RecoverFromPejelagarto does, returning
+// ctx.Err() if cancelled between readings
+func (t *Translator) RecoverFromPejelagartoContext(ctx context.Context, input string) ([]RecoveryCandidate, error) {
+	input = strings.ToValidUTF8(input, string(utf8.RuneError))
+	rules, err := t.decodingRules(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hidden characters the text still has are kept, they hold the timestamp and the locality
+	var hidden, typed strings.Builder
+	if t.opts.DisableTimestamp {
+		typed.WriteString(input)
+	} else {
+		runes := []rune(input)
+		mask, _ := hiddenTimestampMask(runes)
+		for i, r := range runes {
+			if mask[i] {
+				hidden.WriteRune(r)
+			} else {
+				typed.WriteRune(r)
+			}
+		}
+	}
+	localities := []Locality{t.locality(input)}
+	if !t.opts.DisableTimestamp && hidden.Len() == 0 {
+		localities = []Locality{LocalityText, LocalitySentence, LocalityParagraph}
+	}
+	// withOptions returns a translator of the dialect the text is read with
+	withOptions := func(change func(*Options)) *Translator {
+		opts := t.opts
+		if hidden.Len() == 0 {
+			opts.DisableTimestamp = true
+		}
+		change(&opts)
+		return &Translator{opts: opts, compiled: rules, stages: t.stages}
+	}
+
+	// The Human letters do not depend on the diacritics, the case pattern or the locality: the
+	// typed text stripped of its diacritics reads as the Human text with the typed case
+	// Punctuation in a word opened by a quote is left as typed when the quote is the Human text's,
+	// but replaced when the replacement stage wrote it, so both readings are tried
+	plains := []string{typed.String()}
+	if !t.opts.DisablePunctuation {
+		escaped := t.escapeNumberPunctuation(typed.String())
+		plains = []string{rules.pejelagartoPunctuation(escaped, true)}
+		if folded := rules.pejelagartoPunctuation(escaped, false); folded != plains[0] {
+			plains = append(plains, folded)
+		}
+	}
+
+	var candidates []RecoveryCandidate
+	seen := make(map[string]bool)
+	want := rules.typedForm(typed.String())
+	add := func(text, canonical string, locality Locality, typedCase bool) {
+		if seen[text] {
+			return
+		}
+		seen[text] = true
+		withoutTimestamp := text
+		if hidden.Len() > 0 {
+			withoutTimestamp, _ = removeISO8601timestamp(text)
+		}
+		encoder := withOptions(func(opts *Options) { opts.Locality, opts.DisableTimestamp = locality, true })
+		got := rules.typedForm(encoder.ToPejelagarto(withoutTimestamp))
+		candidates = append(candidates, RecoveryCandidate{
+			Text:       text,
+			Canonical:  canonical,
+			Locality:   locality,
+			TypedCase:  typedCase,
+			Consistent: got == want || !typedCase && strings.EqualFold(got, want),
+			Penalty:    casePenalty(withoutTimestamp),
+		})
+	}
+
+	// A text that kept its hidden characters was copied rather than typed, it may be well-formed
+	if hidden.Len() > 0 {
+		add(t.FromPejelagarto(input), typed.String(), localities[0], true)
+	}
+	for _, plain := range plains {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !t.opts.DisableAccents {
+			plain = rules.wheels.strip(plain)
+		}
+		if !t.opts.DisableConsonants {
+			plain = rules.consonants.strip(plain)
+		}
+		keptCase := withOptions(func(opts *Options) {
+			opts.DisableAccents, opts.DisableConsonants, opts.DisableCase = true, true, true
+		}).FromPejelagarto(plain + hidden.String())
+		human := keptCase
+		if hidden.Len() > 0 {
+			human, _ = removeISO8601timestamp(keptCase)
+		}
+
+		// The typed case is the translator's: with its diacritics back the text is the canonical form,
+		// which the encoder writes for the Human text read with the typed case and the case stage left out
+		if !t.opts.DisableCase {
+			for _, locality := range localities {
+				canonical := withOptions(func(opts *Options) {
+					opts.Locality, opts.DisableTimestamp, opts.DisableCase = locality, true, true
+				}).ToPejelagarto(human)
+				text := withOptions(func(opts *Options) { opts.Locality = locality }).FromPejelagarto(canonical + hidden.String())
+				add(text, canonical, locality, true)
+			}
+		}
+		// The typed case is the Human text's
+		canonical := withOptions(func(opts *Options) {
+			opts.Locality, opts.DisableTimestamp = localities[0], true
+		}).ToPejelagarto(human)
+		add(keptCase, canonical, localities[0], false)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Consistent != candidates[j].Consistent {
+			return candidates[i].Consistent
+		}
+		return candidates[i].Penalty < candidates[j].Penalty
+	})
+	return candidates, nil
+}
+
+// escapeNumberPunctuation escapes the punctuation of number literals, e.g. the point of 3.14, as the
+// punctuation stage and then the replacement stage do; escapes are invisible, so they are never typed
+func (t *Translator) escapeNumberPunctuation(input string) string {
+	escape := string(OutputEscapeChar)
+	if !t.opts.DisableMapReplacements {
+		escape += escape
+	}
+	runes := []rune(input)
+	numbers := numberLiteralPunctuation(runes)
+	var result strings.Builder
+	for i, r := range runes {
+		if numbers[i] && !escapedAt(runes, i) {
+			result.WriteString(escape)
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+// pejelagartoPunctuation replaces the Human punctuation that has a single rune and is typed for a
+// Pejelagarto form outside ASCII, e.g. '?' for '‽'; the encoder only leaves it after an escape
+// character or, when quotes is set, in a word opened by a quote, which the replacement engine does not match
+func (c *compiledRules) pejelagartoPunctuation(input string, quotes bool) string {
+	runes := []rune(input)
+	claims := make([]int32, len(runes))
+	var result strings.Builder
+	for i, r := range runes {
+		value, ok := c.punctuation[string(r)]
+		if ok && !isASCII(value) && !isEscapedAt(runes, i) && !(quotes && inQuotedWord(runes, claims, i, 1)) {
+			result.WriteString(value)
+			continue
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+// typedForm returns what remains of a Pejelagarto text typed on a plain keyboard: no escapes,
+// diacritics or hidden characters, and Human punctuation for the forms outside ASCII
+func (c *compiledRules) typedForm(input string) string {
+	text := strings.ReplaceAll(RemoveTimestampSpecialCharacters(input), string(OutputEscapeChar), "")
+	text = c.consonants.strip(c.wheels.strip(text))
+	for key, value := range c.punctuation {
+		if !isASCII(value) {
+			text = strings.ReplaceAll(text, value, key)
+		}
+	}
+	return text
+}
+
+// strip replaces every vowel form of the wheels with its base vowel, keeping its case
+func (w accentWheels) strip(input string) string {
+	runes := []rune(input)
+	starts := accentClusters(runes)
+	var result strings.Builder
+	for i, start := range starts {
+		end := len(runes)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		cluster := runes[start:end]
+		if !w.isVowel(cluster) {
+			result.WriteString(string(cluster))
+			continue
+		}
+		base := w.bases[lowerCluster(cluster)]
+		if unicode.IsUpper(cluster[0]) {
+			base = unicode.ToUpper(base)
+		}
+		result.WriteRune(base)
+	}
+	return result.String()
+}
+
+// strip replaces every consonant form of the wheels with its base consonant, keeping its case
+func (w consonantWheels) strip(input string) string {
+	runes := []rune(input)
+	for i, r := range runes {
+		lower := unicode.ToLower(r)
+		if unicode.IsUpper(r) && unicode.ToUpper(lower) != r {
+			continue
+		}
+		// Forms are single runes, a consonant followed by combining marks is left alone
+		if i+1 < len(runes) && unicode.Is(unicode.M, runes[i+1]) {
+			continue
+		}
+		if base, ok := w.bases[string(lower)]; ok {
+			if unicode.IsUpper(r) {
+				base = unicode.ToUpper(base)
+			}
+			runes[i] = base
+		}
+	}
+	return string(runes)
+}
+
+// casePenalty counts the words whose case is not lower, upper or capitalized
+func casePenalty(text string) int {
+	penalty := 0
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		letters := []rune(word)
+		rest := string(letters[1:])
+		if rest != strings.ToLower(rest) && rest != strings.ToUpper(rest) {
+			penalty++
+		} else if unicode.IsLower(letters[0]) && rest != strings.ToLower(rest) {
+			penalty++
+		}
+	}
+	return penalty
+}
+
+// isASCII reports whether s only holds ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package translator
+
+import (
+	"testing"
+	"unicode"
+)
+
+// FuzzRecoverFromPejelagarto tests that a Human text without diacritics is among the readings of its
+// Pejelagarto typed without accents, hidden characters and escapes, in every locality
+func FuzzRecoverFromPejelagarto(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Hello world! How are you? I'm fine, thanks - really (yes).", uint8(0))
+	f.Add("Meet me at the north gate at noon. Bring the map.", uint8(1))
+	f.Add("The quick brown fox\njumps over the lazy dog", uint8(2))
+	f.Fuzz(func(t *testing.T, input string, locality uint8) {
+		for _, r := range input {
+			if r > unicode.MaxASCII || (r < ' ' && r != '\n') {
+				return
+			}
+		}
+		localities := []Locality{LocalityText, LocalitySentence, LocalityParagraph}
+		tr := fixedTestTranslator(Options{Locality: localities[int(locality)%len(localities)]})
+		pejelagarto := tr.ToPejelagarto(input)
+		typed := currentRules(t).typedForm(pejelagarto)
+
+		candidates := New(Options{}).RecoverFromPejelagarto(typed)
+		for _, candidate := range candidates {
+			if candidate.Text == input && candidate.TypedCase && candidate.Consistent {
+				return
+			}
+		}
+		t.Errorf("no consistent reading gives back the input\nInput:       %q\nPejelagarto: %q\nTyped:       %q\nCandidates:  %+v", input, pejelagarto, typed, candidates)
+	})
+}
+
+func TestRecoverFromPejelagarto(t *testing.T) {
+	human := "Meet me at the north gate at noon. Bring the map!"
+	tr := fixedTestTranslator(Options{Locality: LocalitySentence})
+	pejelagarto := tr.ToPejelagarto(human)
+
+	// Typed on a plain keyboard, the best reading is the Human text
+	candidates := RecoverFromPejelagarto(currentRules(t).typedForm(pejelagarto))
+	if len(candidates) == 0 || candidates[0].Text != human || !candidates[0].Consistent {
+		t.Errorf("RecoverFromPejelagarto(typed) = %+v, want %q first", candidates, human)
+	}
+	// Plain FromPejelagarto cannot read it
+	if decoded := TranslateFromPejelagarto(currentRules(t).typedForm(pejelagarto)); decoded == human {
+		t.Errorf("FromPejelagarto read the typed text back, the test text is too easy")
+	}
+
+	// A copied text is first read as FromPejelagarto does
+	candidates = tr.RecoverFromPejelagarto(pejelagarto)
+	if len(candidates) == 0 || candidates[0].Text != tr.FromPejelagarto(pejelagarto) {
+		t.Errorf("RecoverFromPejelagarto(copied) = %+v, want the FromPejelagarto reading first", candidates)
+	}
+
+	// Without the case stage the typed case is the Human text's
+	uncased := New(Options{DisableCase: true, DisableTimestamp: true})
+	candidates = uncased.RecoverFromPejelagarto(currentRules(t).typedForm(uncased.ToPejelagarto(human)))
+	if len(candidates) != 1 || candidates[0].Text != human || candidates[0].TypedCase {
+		t.Errorf("RecoverFromPejelagarto(uncased) = %+v, want only %q", candidates, human)
+	}
+}
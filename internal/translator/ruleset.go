@@ -0,0 +1,553 @@
+package translator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"gopkg.in/yaml.v3"
+)
+
+// Ruleset describes a Pejelagarto dialect: the replacement maps of every source script, the accent
+// and consonant wheels and the escape characters used by the translation pipeline
+// The built-in dialect is available through DefaultRuleset
+type Ruleset struct {
+	Name                   string
+	ConjunctionMap         map[string]string
+	LetterMap              map[string]string
+	CyrillicConjunctionMap map[string]string
+	CyrillicLetterMap      map[string]string
+	GreekConjunctionMap    map[string]string
+	GreekLetterMap         map[string]string
+	PunctuationMap         map[string]string
+	OneRuneAccentsWheel    map[rune][]string
+	TwoRunesAccentsWheel   map[rune][]string
+	ConsonantWheel         map[rune][]string
+	InternalEscapeChar     rune
+	OutputEscapeChar       rune
+}
+
+// builtinRuleset is a snapshot of the hard-coded dialect, taken before any ruleset is applied
+var builtinRuleset = CurrentRuleset()
+
+// currentRulesetName is the name of the dialect installed by UseRuleset
+var currentRulesetName = "default"
+
+// DefaultRuleset returns a copy of the built-in Pejelagarto dialect
+func DefaultRuleset() *Ruleset {
+	return builtinRuleset.Clone()
+}
+
+// CurrentRuleset returns a copy of the dialect currently used by the package-level translation functions
+func CurrentRuleset() *Ruleset {
+	return &Ruleset{
+		Name:                   currentRulesetName,
+		ConjunctionMap:         copyStringMap(ConjunctionMap),
+		LetterMap:              copyStringMap(LetterMap),
+		CyrillicConjunctionMap: copyStringMap(CyrillicConjunctionMap),
+		CyrillicLetterMap:      copyStringMap(CyrillicLetterMap),
+		GreekConjunctionMap:    copyStringMap(GreekConjunctionMap),
+		GreekLetterMap:         copyStringMap(GreekLetterMap),
+		PunctuationMap:         copyStringMap(PunctuationMap),
+		OneRuneAccentsWheel:    copyWheel(OneRuneAccentsWheel),
+		TwoRunesAccentsWheel:   copyWheel(TwoRunesAccentsWheel),
+		ConsonantWheel:         copyWheel(ConsonantWheel),
+		InternalEscapeChar:     InternalEscapeChar,
+		OutputEscapeChar:       OutputEscapeChar,
+	}
+}
+
+// Clone returns a deep copy of the ruleset
+func (rs *Ruleset) Clone() *Ruleset {
+	return &Ruleset{
+		Name:                   rs.Name,
+		ConjunctionMap:         copyStringMap(rs.ConjunctionMap),
+		LetterMap:              copyStringMap(rs.LetterMap),
+		CyrillicConjunctionMap: copyStringMap(rs.CyrillicConjunctionMap),
+		CyrillicLetterMap:      copyStringMap(rs.CyrillicLetterMap),
+		GreekConjunctionMap:    copyStringMap(rs.GreekConjunctionMap),
+		GreekLetterMap:         copyStringMap(rs.GreekLetterMap),
+		PunctuationMap:         copyStringMap(rs.PunctuationMap),
+		OneRuneAccentsWheel:    copyWheel(rs.OneRuneAccentsWheel),
+		TwoRunesAccentsWheel:   copyWheel(rs.TwoRunesAccentsWheel),
+		ConsonantWheel:         copyWheel(rs.ConsonantWheel),
+		InternalEscapeChar:     rs.InternalEscapeChar,
+		OutputEscapeChar:       rs.OutputEscapeChar,
+	}
+}
+
+// UseRuleset validates the ruleset and makes it the dialect used by the package-level translation functions
+// It is meant to be called once at startup, before any translation runs
+func UseRuleset(rs *Ruleset) error {
+	if err := rs.Validate(); err != nil {
+		return err
+	}
+
+	ConjunctionMap = copyStringMap(rs.ConjunctionMap)
+	LetterMap = copyStringMap(rs.LetterMap)
+	CyrillicConjunctionMap = copyStringMap(rs.CyrillicConjunctionMap)
+	CyrillicLetterMap = copyStringMap(rs.CyrillicLetterMap)
+	GreekConjunctionMap = copyStringMap(rs.GreekConjunctionMap)
+	GreekLetterMap = copyStringMap(rs.GreekLetterMap)
+	PunctuationMap = copyStringMap(rs.PunctuationMap)
+	OneRuneAccentsWheel = copyWheel(rs.OneRuneAccentsWheel)
+	TwoRunesAccentsWheel = copyWheel(rs.TwoRunesAccentsWheel)
+	ConsonantWheel = copyWheel(rs.ConsonantWheel)
+	InternalEscapeChar = rs.InternalEscapeChar
+	OutputEscapeChar = rs.OutputEscapeChar
+	currentRulesetName = rs.Name
+	resetCompiledRules()
+	return nil
+}
+
+// RulesetIssue describes a single problem found while loading or validating a ruleset
+type RulesetIssue struct {
+	Section string // e.g. "conjunctions", "cyrillic_letters", "accent_wheels"
+	Key     string // offending key, empty when the issue concerns the whole section
+	Message string
+}
+
+func (issue RulesetIssue) String() string {
+	if issue.Key == "" {
+		return fmt.Sprintf("%s: %s", issue.Section, issue.Message)
+	}
+	return fmt.Sprintf("%s[%q]: %s", issue.Section, issue.Key, issue.Message)
+}
+
+// RulesetError is returned when a ruleset cannot be loaded or fails validation
+// It carries every issue found, not only the first one
+type RulesetError struct {
+	Name   string
+	Issues []RulesetIssue
+}
+
+func (e *RulesetError) Error() string {
+	lines := make([]string, 0, len(e.Issues)+1)
+	lines = append(lines, fmt.Sprintf("ruleset %q is invalid (%d issues):", e.Name, len(e.Issues)))
+	for _, issue := range e.Issues {
+		lines = append(lines, "  - "+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// rulesetFile is the on-disk JSON/YAML representation of a Ruleset
+// Sections left out of the file are inherited from the built-in dialect
+type rulesetFile struct {
+	Name                 string              `json:"name" yaml:"name"`
+	Conjunctions         map[string]string   `json:"conjunctions" yaml:"conjunctions"`
+	Letters              map[string]string   `json:"letters" yaml:"letters"`
+	CyrillicConjunctions map[string]string   `json:"cyrillic_conjunctions" yaml:"cyrillic_conjunctions"`
+	CyrillicLetters      map[string]string   `json:"cyrillic_letters" yaml:"cyrillic_letters"`
+	GreekConjunctions    map[string]string   `json:"greek_conjunctions" yaml:"greek_conjunctions"`
+	GreekLetters         map[string]string   `json:"greek_letters" yaml:"greek_letters"`
+	Punctuation          map[string]string   `json:"punctuation" yaml:"punctuation"`
+	AccentWheels         map[string][]string `json:"accent_wheels" yaml:"accent_wheels"`
+	TwoRuneAccentWheels  map[string][]string `json:"two_rune_accent_wheels" yaml:"two_rune_accent_wheels"`
+	ConsonantWheels      map[string][]string `json:"consonant_wheels" yaml:"consonant_wheels"`
+	EscapeChars          *struct {
+		Internal string `json:"internal" yaml:"internal"`
+		Output   string `json:"output" yaml:"output"`
+	} `json:"escape_chars" yaml:"escape_chars"`
+}
+
+// LoadRuleset reads a ruleset from a .json, .yaml or .yml file and validates it
+func LoadRuleset(path string) (*Ruleset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading ruleset: %w", err)
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	rs, err := ParseRuleset(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if rs.Name == "" {
+		rs.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return rs, nil
+}
+
+// ParseRuleset decodes a ruleset in the given format ("json", "yaml" or "yml") and validates it
+func ParseRuleset(data []byte, format string) (*Ruleset, error) {
+	var file rulesetFile
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parsing ruleset JSON: %w", err)
+		}
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parsing ruleset YAML: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ruleset format %q (expected json or yaml)", format)
+	}
+
+	rs := DefaultRuleset()
+	rs.Name = file.Name
+	var issues []RulesetIssue
+
+	if file.Conjunctions != nil {
+		rs.ConjunctionMap = file.Conjunctions
+	}
+	if file.Letters != nil {
+		rs.LetterMap = file.Letters
+	}
+	if file.CyrillicConjunctions != nil {
+		rs.CyrillicConjunctionMap = file.CyrillicConjunctions
+	}
+	if file.CyrillicLetters != nil {
+		rs.CyrillicLetterMap = file.CyrillicLetters
+	}
+	if file.GreekConjunctions != nil {
+		rs.GreekConjunctionMap = file.GreekConjunctions
+	}
+	if file.GreekLetters != nil {
+		rs.GreekLetterMap = file.GreekLetters
+	}
+	if file.Punctuation != nil {
+		rs.PunctuationMap = file.Punctuation
+	}
+	if file.AccentWheels != nil {
+		rs.OneRuneAccentsWheel, issues = parseWheel("accent_wheels", file.AccentWheels, issues)
+	}
+	if file.TwoRuneAccentWheels != nil {
+		rs.TwoRunesAccentsWheel, issues = parseWheel("two_rune_accent_wheels", file.TwoRuneAccentWheels, issues)
+	}
+	if file.ConsonantWheels != nil {
+		rs.ConsonantWheel, issues = parseWheel("consonant_wheels", file.ConsonantWheels, issues)
+	}
+	if file.EscapeChars != nil {
+		rs.InternalEscapeChar, issues = parseEscapeChar("internal", file.EscapeChars.Internal, issues)
+		rs.OutputEscapeChar, issues = parseEscapeChar("output", file.EscapeChars.Output, issues)
+	}
+
+	var validationErr *RulesetError
+	if errors.As(rs.Validate(), &validationErr) {
+		issues = append(issues, validationErr.Issues...)
+	}
+	if len(issues) > 0 {
+		return nil, &RulesetError{Name: rs.Name, Issues: issues}
+	}
+	return rs, nil
+}
+
+// parseWheel converts string-keyed wheels from a ruleset file into rune-keyed wheels
+func parseWheel(section string, wheels map[string][]string, issues []RulesetIssue) (map[rune][]string, []RulesetIssue) {
+	result := make(map[rune][]string, len(wheels))
+	for key, forms := range wheels {
+		if utf8.RuneCountInString(key) != 1 {
+			issues = append(issues, RulesetIssue{section, key, "wheel key must be exactly 1 character"})
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(key)
+		result[r] = forms
+	}
+	return result, issues
+}
+
+// parseEscapeChar converts an escape character from a ruleset file into a rune
+func parseEscapeChar(key string, value string, issues []RulesetIssue) (rune, []RulesetIssue) {
+	if utf8.RuneCountInString(value) != 1 {
+		issues = append(issues, RulesetIssue{"escape_chars", key, "escape character must be exactly 1 character"})
+		return 0, issues
+	}
+	r, _ := utf8.DecodeRuneInString(value)
+	return r, issues
+}
+
+// Validate checks every constraint the translation pipeline relies on for reversibility
+// It returns a *RulesetError listing all issues found, or nil if the ruleset is usable
+func (rs *Ruleset) Validate() error {
+	return rs.validate(false)
+}
+
+// ValidateStrict runs Validate and also checks the maps for collisions: lowercase entries, vowels
+// paired with vowels, conjunction values written with the letters the letter map leaves alone,
+// without repeated neighbours, and none starting another
+// The checks are opt-in: the built-in dialect has kept such collisions since its first release
+// ("arak" starts "araka", so "holau" is read back as "hello"), and its texts must keep decoding
+func (rs *Ruleset) ValidateStrict() error {
+	return rs.validate(true)
+}
+
+// validate checks the ruleset, with the collision checks of ValidateStrict when collisions is set
+func (rs *Ruleset) validate(collisions bool) error {
+	var issues []RulesetIssue
+	add := func(section, key, format string, args ...interface{}) {
+		issues = append(issues, RulesetIssue{section, key, fmt.Sprintf(format, args...)})
+	}
+
+	checkLowercase := func(section, key, value string) {
+		if key != strings.ToLower(key) || value != strings.ToLower(value) {
+			add(section, key, "key and value %q must be lowercase, the case of the text is applied to them", value)
+		}
+	}
+
+	// Conjunctions and letters of every script: written in their script, so that the maps of two
+	// scripts never compete for the same text
+	conjunctionValues := make(map[string]string)
+	conjunctionSections := make(map[string]string)
+	for _, script := range sourceScripts {
+		conjunctionMap, letterMap := script.maps(rs)
+		checkScript := func(section, key, text string) {
+			for _, r := range text {
+				if unicode.IsLetter(r) && !unicode.Is(script.table, r) {
+					add(section, key, "letter %q is not written in the %s script", r, script.name)
+					return
+				}
+			}
+		}
+
+		// Conjunctions: non-empty, equal rune lengths, no duplicate values
+		section := script.conjunctionSection
+		for _, key := range sortedKeys(*conjunctionMap) {
+			value := (*conjunctionMap)[key]
+			keyLen := utf8.RuneCountInString(key)
+			valueLen := utf8.RuneCountInString(value)
+			if keyLen == 0 {
+				add(section, key, "key must not be empty")
+				continue
+			}
+			if keyLen != valueLen {
+				add(section, key, "key (len=%d) and value %q (len=%d) must have equal rune lengths", keyLen, value, valueLen)
+			}
+			if containsEscapeChar(rs, key+value) {
+				add(section, key, "must not contain an escape character")
+			}
+			if existingKey, exists := conjunctionValues[strings.ToLower(value)]; exists {
+				add(section, key, "value %q is already used by key %q (not bijective)", value, existingKey)
+			}
+			conjunctionValues[strings.ToLower(value)] = key
+			conjunctionSections[strings.ToLower(value)] = section
+			checkScript(section, key, key+value)
+			if collisions {
+				checkLowercase(section, key, value)
+				// A letter of the letter map in a value would also be produced by that letter
+				var previous rune
+				for _, r := range value {
+					if _, mapped := (*letterMap)[string(r)]; mapped {
+						add(section, key, "value %q uses %q, a letter of the letter map", value, r)
+						break
+					}
+					if r == previous {
+						add(section, key, "value %q repeats %q, which could be read as two letters", value, r)
+						break
+					}
+					previous = r
+				}
+			}
+		}
+
+		// Letters: single runes forming true bijective pairs
+		section = script.letterSection
+		for _, key := range sortedKeys(*letterMap) {
+			value := (*letterMap)[key]
+			if utf8.RuneCountInString(key) != 1 {
+				add(section, key, "key must be exactly 1 rune")
+				continue
+			}
+			if utf8.RuneCountInString(value) != 1 {
+				add(section, key, "value %q must be exactly 1 rune", value)
+				continue
+			}
+			if containsEscapeChar(rs, key+value) {
+				add(section, key, "must not contain an escape character")
+			}
+			if reverse, exists := (*letterMap)[value]; !exists || reverse != key {
+				add(section, key, "value %q must map back to %q (true bijective pairs)", value, key)
+			}
+			checkScript(section, key, key+value)
+			if collisions {
+				checkLowercase(section, key, value)
+				if script.isVowel(key) != script.isVowel(value) {
+					add(section, key, "value %q must be a vowel if and only if the key is (vowels map to vowels)", value)
+				}
+			}
+		}
+	}
+
+	// Conjunction values are quoted in the output: a value that starts another is read back as the
+	// longer one when the letters written after it complete it
+	if collisions {
+		values := sortedKeys(conjunctionValues)
+		for i, value := range values {
+			for _, longer := range values[i+1:] {
+				if strings.HasPrefix(longer, value) {
+					add(conjunctionSections[longer], conjunctionValues[longer], "value %q starts with %q, the value of %q",
+						longer, value, conjunctionValues[value])
+				}
+			}
+		}
+	}
+
+	// Punctuation: non-empty, no duplicate values, no escape characters
+	punctuationValues := make(map[string]string)
+	for _, key := range sortedKeys(rs.PunctuationMap) {
+		value := rs.PunctuationMap[key]
+		if key == "" || value == "" {
+			add("punctuation", key, "keys and values must not be empty")
+			continue
+		}
+		if existingKey, exists := punctuationValues[value]; exists {
+			add("punctuation", key, "value %q is already used by key %q (not bijective)", value, existingKey)
+		}
+		punctuationValues[value] = key
+		if containsEscapeChar(rs, key+value) {
+			add("punctuation", key, "must not contain an escape character")
+		}
+	}
+
+	// Accent wheels: rune counts, reversible case, base vowel first, no form shared between wheels
+	seenForms := make(map[string]rune)
+	checkWheel := func(section string, wheel map[rune][]string, expectedRunes int) {
+		for _, base := range sortedWheelKeys(wheel) {
+			forms := wheel[base]
+			key := string(base)
+			if expectedRunes == 1 && (len(forms) == 0 || forms[0] != key) {
+				add(section, key, "wheel must start with the base letter %q", key)
+			}
+			for idx, form := range forms {
+				runes := []rune(form)
+				if len(runes) != expectedRunes {
+					add(section, key, "form %d %q has %d runes, expected %d", idx, form, len(runes), expectedRunes)
+					continue
+				}
+				if unicode.ToLower(unicode.ToUpper(runes[0])) != runes[0] {
+					add(section, key, "form %d %q has non-reversible case conversion", idx, form)
+				}
+				if expectedRunes == 2 && (unicode.Is(unicode.M, runes[0]) || !unicode.Is(unicode.M, runes[1])) {
+					add(section, key, "form %d %q must be a vowel followed by a combining mark", idx, form)
+				}
+				if owner, exists := seenForms[form]; exists {
+					add(section, key, "form %q already belongs to the wheel of %q", form, string(owner))
+				}
+				seenForms[form] = base
+			}
+		}
+	}
+	checkWheel("accent_wheels", rs.OneRuneAccentsWheel, 1)
+	checkWheel("two_rune_accent_wheels", rs.TwoRunesAccentsWheel, 2)
+	for base := range rs.TwoRunesAccentsWheel {
+		if _, exists := rs.OneRuneAccentsWheel[base]; !exists {
+			add("two_rune_accent_wheels", string(base), "base vowel has no single-rune accent wheel")
+		}
+	}
+
+	// Consonant wheels: the same checks, and every form is its base letter with a diacritic, so that a
+	// consonant never turns into another letter and the letters the maps keep apart stay apart
+	checkWheel("consonant_wheels", rs.ConsonantWheel, 1)
+	mapLetters := make(map[string]bool)
+	for _, m := range rs.replacementMaps() {
+		for key, value := range m {
+			for _, r := range strings.ToLower(key + value) {
+				mapLetters[string(r)] = true
+			}
+		}
+	}
+	for _, base := range sortedWheelKeys(rs.ConsonantWheel) {
+		key := string(base)
+		if _, isVowel := rs.OneRuneAccentsWheel[base]; isVowel {
+			add("consonant_wheels", key, "base consonant %q has an accent wheel", key)
+		}
+		for idx, form := range rs.ConsonantWheel[base] {
+			if decomposed := []rune(norm.NFD.String(form)); decomposed[0] != base {
+				add("consonant_wheels", key, "form %d %q is not a form of the letter %q", idx, form, key)
+			}
+			if idx > 0 && mapLetters[form] {
+				add("consonant_wheels", key, "form %d %q is used by the letter or conjunction maps", idx, form)
+			}
+		}
+	}
+
+	// Escape characters: distinct and unused by the timestamp encoding
+	if rs.InternalEscapeChar == rs.OutputEscapeChar {
+		add("escape_chars", "", "internal and output escape characters must be different, both are %q", rs.InternalEscapeChar)
+	}
+	for _, chars := range timestampSpecialCharTables() {
+		for _, char := range chars {
+			if char == string(rs.InternalEscapeChar) || char == string(rs.OutputEscapeChar) {
+				add("escape_chars", "", "escape character %q is used by the timestamp encoding", char)
+			}
+		}
+	}
+
+	// Timestamp characters must never be produced by the letter and conjunction maps
+	mapChars := make(map[rune]string)
+	for _, script := range sourceScripts {
+		conjunctionMap, letterMap := script.maps(rs)
+		for _, m := range []struct {
+			section string
+			m       map[string]string
+		}{{script.letterSection, *letterMap}, {script.conjunctionSection, *conjunctionMap}} {
+			for key, value := range m.m {
+				for _, r := range key + value {
+					mapChars[r] = m.section
+				}
+			}
+		}
+	}
+	for _, chars := range timestampSpecialCharTables() {
+		for _, char := range chars {
+			for _, r := range char {
+				if section, exists := mapChars[r]; exists {
+					add(section, string(r), "character is reserved for the timestamp encoding")
+				}
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return &RulesetError{Name: rs.Name, Issues: issues}
+	}
+	return nil
+}
+
+// containsEscapeChar reports whether s uses one of the ruleset's escape characters
+func containsEscapeChar(rs *Ruleset, s string) bool {
+	return strings.ContainsRune(s, rs.InternalEscapeChar) || strings.ContainsRune(s, rs.OutputEscapeChar)
+}
+
+// sortedKeys returns the keys of a string map in a stable order for deterministic reporting
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedWheelKeys returns the base letters of wheels in a stable order for deterministic reporting
+func sortedWheelKeys(wheels map[rune][]string) []rune {
+	bases := make([]rune, 0, len(wheels))
+	for base := range wheels {
+		bases = append(bases, base)
+	}
+	sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+	return bases
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	result := make(map[string]string, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
+
+func copyWheel(wheel map[rune][]string) map[rune][]string {
+	result := make(map[rune][]string, len(wheel))
+	for base, forms := range wheel {
+		result[base] = append([]string(nil), forms...)
+	}
+	return result
+}
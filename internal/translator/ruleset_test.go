@@ -0,0 +1,183 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestDefaultRulesetIsValid verifies the built-in dialect passes its own validation
+func TestDefaultRulesetIsValid(t *testing.T) {
+	if err := DefaultRuleset().Validate(); err != nil {
+		t.Fatalf("built-in ruleset failed validation: %v", err)
+	}
+}
+
+// TestParseRuleset verifies JSON and YAML rulesets override only the sections they define
+func TestParseRuleset(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{
+			name:   "JSON",
+			format: "json",
+			data:   `{"name": "house", "conjunctions": {"ch": "jc", "sh": "xs"}}`,
+		},
+		{
+			name:   "YAML",
+			format: "yaml",
+			data:   "name: house\nconjunctions:\n  ch: jc\n  sh: xs\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rs, err := ParseRuleset([]byte(tc.data), tc.format)
+			if err != nil {
+				t.Fatalf("ParseRuleset() unexpected error: %v", err)
+			}
+			if rs.Name != "house" {
+				t.Errorf("Name = %q, want %q", rs.Name, "house")
+			}
+			if len(rs.ConjunctionMap) != 2 {
+				t.Errorf("ConjunctionMap has %d entries, want 2", len(rs.ConjunctionMap))
+			}
+			if len(rs.LetterMap) != len(LetterMap) {
+				t.Errorf("LetterMap was not inherited from the built-in dialect")
+			}
+		})
+	}
+}
+
+// TestParseRulesetReportsAllIssues verifies invalid rulesets return structured errors instead of panicking
+func TestParseRulesetReportsAllIssues(t *testing.T) {
+	data := `{
+		"conjunctions": {"the": "el"},
+		"letters": {"a": "u"},
+		"accent_wheels": {"ab": ["a"]},
+		"escape_chars": {"internal": "\\", "output": "\\"}
+	}`
+
+	_, err := ParseRuleset([]byte(data), "json")
+	var rulesetErr *RulesetError
+	if !errors.As(err, &rulesetErr) {
+		t.Fatalf("ParseRuleset() error = %v, want *RulesetError", err)
+	}
+	if len(rulesetErr.Issues) < 2 {
+		t.Errorf("expected every issue to be reported, got %d: %v", len(rulesetErr.Issues), rulesetErr)
+	}
+
+	data = `{"conjunctions": {"the": "el"}, "letters": {"a": "u"}}`
+	_, err = ParseRuleset([]byte(data), "json")
+	if !errors.As(err, &rulesetErr) {
+		t.Fatalf("ParseRuleset() error = %v, want *RulesetError", err)
+	}
+	sections := make(map[string]bool)
+	for _, issue := range rulesetErr.Issues {
+		sections[issue.Section] = true
+	}
+	if !sections["conjunctions"] || !sections["letters"] {
+		t.Errorf("expected conjunction and letter issues, got %v", rulesetErr)
+	}
+
+	// A consonant must only move to forms of its own letter
+	data = `{"consonant_wheels": {"c": ["c", "ç", "ñ"], "a": ["a"]}}`
+	_, err = ParseRuleset([]byte(data), "json")
+	if !errors.As(err, &rulesetErr) {
+		t.Fatalf("ParseRuleset() error = %v, want *RulesetError", err)
+	}
+	if len(rulesetErr.Issues) != 3 {
+		t.Errorf("expected the foreign form, the shared form and the vowel base to be reported, got %v", rulesetErr)
+	}
+}
+
+// TestValidateStrict verifies the collision checks are opt-in: the built-in dialect keeps its
+// collisions, and older versions are registered without them
+func TestValidateStrict(t *testing.T) {
+	if err := DefaultRuleset().Validate(); err != nil {
+		t.Fatalf("Validate() = %v for the built-in dialect", err)
+	}
+	var rulesetErr *RulesetError
+	if !errors.As(DefaultRuleset().ValidateStrict(), &rulesetErr) || !strings.Contains(rulesetErr.Error(), `starts with "arak"`) {
+		t.Errorf("ValidateStrict() = %v for the built-in dialect, want its \"arak\" prefix reported", rulesetErr)
+	}
+
+	// Conjunction values free of collisions, so that each case reports its own issue
+	strict := func() *Ruleset {
+		rs := DefaultRuleset()
+		rs.ConjunctionMap = map[string]string{
+			"hello": "xjtch", "hola": "zhcs", "fran": "tshj", "the": "sjt", "el": "hz",
+			"la": "tx", "leg": "cxh", "ch": "jc", "sh": "xs", "th": "zt",
+		}
+		return rs
+	}
+	if err := strict().ValidateStrict(); err != nil {
+		t.Fatalf("ValidateStrict() = %v for collision-free values", err)
+	}
+
+	testCases := []struct {
+		name   string
+		change func(rs *Ruleset)
+		want   string
+	}{
+		{"uppercase key", func(rs *Ruleset) { rs.ConjunctionMap["Sh"] = rs.ConjunctionMap["sh"]; delete(rs.ConjunctionMap, "sh") }, "lowercase"},
+		{"vowel to consonant", func(rs *Ruleset) {
+			rs.LetterMap["a"], rs.LetterMap["b"], rs.LetterMap["u"], rs.LetterMap["p"] = "b", "a", "p", "u"
+		}, "vowels map to vowels"},
+		{"letter of the letter map", func(rs *Ruleset) { rs.ConjunctionMap["th"] = "za" }, "a letter of the letter map"},
+		{"repeated neighbour", func(rs *Ruleset) { rs.ConjunctionMap["th"] = "zz" }, "could be read as two letters"},
+		{"value starting another", func(rs *Ruleset) { rs.ConjunctionMap["hola"] = "xjtc" }, `starts with "xjtc"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rs := strict()
+			tc.change(rs)
+			var rulesetErr *RulesetError
+			if !errors.As(rs.ValidateStrict(), &rulesetErr) {
+				t.Fatalf("ValidateStrict() accepted the ruleset")
+			}
+			found := false
+			for _, issue := range rulesetErr.Issues {
+				found = found || strings.Contains(issue.Message, tc.want)
+			}
+			if !found {
+				t.Errorf("ValidateStrict() = %v, want an issue about %q", rulesetErr, tc.want)
+			}
+			if err := rs.Validate(); err != nil {
+				t.Errorf("Validate() = %v, the collision checks are opt-in", err)
+			}
+		})
+	}
+}
+
+// TestInvalidDialectDoesNotPanic verifies an invalid dialect is reported by the translator instead of panicking
+func TestInvalidDialectDoesNotPanic(t *testing.T) {
+	rs := DefaultRuleset()
+	rs.ConjunctionMap["hola"] = "arakan"
+	tr := New(Options{Ruleset: rs})
+	if err := tr.Err(); err == nil {
+		t.Errorf("Err() = nil for an invalid Options.Ruleset")
+	}
+	if _, err := tr.ToPejelagartoContext(context.Background(), "hello"); err == nil {
+		t.Errorf("ToPejelagartoContext() error = nil for an invalid Options.Ruleset")
+	}
+
+	// The exported maps can be edited directly
+	previous := LetterMap["a"]
+	LetterMap["a"] = "b"
+	resetCompiledRules()
+	t.Cleanup(func() {
+		LetterMap["a"] = previous
+		resetCompiledRules()
+	})
+	if err := New(Options{}).Err(); err == nil {
+		t.Errorf("Err() = nil with an invalid letter map")
+	}
+	if got := TranslateToPejelagarto("hello"); got != "" {
+		t.Errorf("TranslateToPejelagarto() = %q with an invalid letter map, want nothing", got)
+	}
+}
@@ -0,0 +1,166 @@
+package translator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Source scripts
+// Besides the Latin ConjunctionMap and LetterMap, Cyrillic (Russian and Kazakh) and Greek text has
+// its own pair of maps, following the same constraints: equal rune lengths, true bijective letter
+// pairs with vowels mapping to vowels, and conjunction values written only with letters the letter
+// map leaves alone. Each pair stays within its script, so mixed-script text round-trips
+
+// Cyrillic conjunction replacements
+// NOTE: Output values use ONLY letters NOT in CyrillicLetterMap (ж,х,ц,ч,ш,щ,ъ,ь,һ)
+var CyrillicConjunctionMap = map[string]string{
+	"привет": "щъцьхж",
+	"сәлем":  "шьчъж",
+	"және":   "хчһц",
+	"что":    "шцх",
+	"не":     "жь",
+	"ст":     "щх",
+}
+
+// Cyrillic single letter replacements, covering the Russian and Kazakh alphabets
+// NOTE: Vowels map to vowels (і maps to itself), consonants to consonants
+var CyrillicLetterMap = map[string]string{
+	"а": "о",
+	"о": "а",
+	"е": "и",
+	"и": "е",
+	"ё": "ю",
+	"ю": "ё",
+	"у": "ы",
+	"ы": "у",
+	"э": "я",
+	"я": "э",
+	"ә": "ө",
+	"ө": "ә",
+	"ұ": "ү",
+	"ү": "ұ",
+	"і": "і",
+	"б": "п",
+	"п": "б",
+	"в": "ф",
+	"ф": "в",
+	"г": "к",
+	"к": "г",
+	"д": "т",
+	"т": "д",
+	"з": "с",
+	"с": "з",
+	"л": "р",
+	"р": "л",
+	"м": "н",
+	"н": "м",
+	"ғ": "қ",
+	"қ": "ғ",
+	"й": "ң",
+	"ң": "й",
+}
+
+// Greek conjunction replacements
+// NOTE: Output values use ONLY letters NOT in GreekLetterMap (ζ,θ,ξ,φ,χ,ψ)
+var GreekConjunctionMap = map[string]string{
+	"γεια": "ψχζξ",
+	"και":  "θφχ",
+	"το":   "ξψ",
+	"μπ":   "ζθ",
+	"ντ":   "χφ",
+	"ου":   "ψζ",
+}
+
+// Greek single letter replacements; accented vowels map to accented vowels
+// NOTE: σ and ς are left out, the final sigma does not survive case conversion (ς → Σ → σ)
+var GreekLetterMap = map[string]string{
+	"α": "ο",
+	"ο": "α",
+	"ε": "η",
+	"η": "ε",
+	"ι": "υ",
+	"υ": "ι",
+	"ω": "ω",
+	"ά": "ό",
+	"ό": "ά",
+	"έ": "ή",
+	"ή": "έ",
+	"ί": "ύ",
+	"ύ": "ί",
+	"ώ": "ώ",
+	"ϊ": "ϋ",
+	"ϋ": "ϊ",
+	"β": "π",
+	"π": "β",
+	"γ": "κ",
+	"κ": "γ",
+	"δ": "τ",
+	"τ": "δ",
+	"λ": "ρ",
+	"ρ": "λ",
+	"μ": "ν",
+	"ν": "μ",
+}
+
+// sourceScript describes the conjunction and letter maps of one script
+type sourceScript struct {
+	name               string
+	table              *unicode.RangeTable // every letter of the maps belongs to it
+	alphabet           string              // lowercase letters generated dialects draw from
+	vowels             string
+	conjunctionSection string // ruleset file sections
+	letterSection      string
+	maps               func(rs *Ruleset) (conjunctions, letters *map[string]string)
+}
+
+// sourceScripts lists the scripts with replacement maps, Latin first
+var sourceScripts = []sourceScript{
+	{
+		name:               "Latin",
+		table:              unicode.Latin,
+		alphabet:           "abcdefghijklmnopqrstuvwxyz",
+		vowels:             "aeiouwy",
+		conjunctionSection: "conjunctions",
+		letterSection:      "letters",
+		maps: func(rs *Ruleset) (*map[string]string, *map[string]string) {
+			return &rs.ConjunctionMap, &rs.LetterMap
+		},
+	},
+	{
+		name:               "Cyrillic",
+		table:              unicode.Cyrillic,
+		alphabet:           "абвгдеёжзийклмнопрстуфхцчшщъыьэюяәғқңөұүһі",
+		vowels:             "аеёиоуыэюяәөұүі",
+		conjunctionSection: "cyrillic_conjunctions",
+		letterSection:      "cyrillic_letters",
+		maps: func(rs *Ruleset) (*map[string]string, *map[string]string) {
+			return &rs.CyrillicConjunctionMap, &rs.CyrillicLetterMap
+		},
+	},
+	{
+		name:               "Greek",
+		table:              unicode.Greek,
+		alphabet:           "αβγδεζηθικλμνξοπρτυφχψωάέήίόύώϊϋ",
+		vowels:             "αεηιουωάέήίόύώϊϋ",
+		conjunctionSection: "greek_conjunctions",
+		letterSection:      "greek_letters",
+		maps: func(rs *Ruleset) (*map[string]string, *map[string]string) {
+			return &rs.GreekConjunctionMap, &rs.GreekLetterMap
+		},
+	},
+}
+
+// isVowel reports whether a lowercase letter is a vowel of the script
+func (s sourceScript) isVowel(letter string) bool {
+	return strings.Contains(s.vowels, letter)
+}
+
+// replacementMaps returns the conjunction and letter maps of every source script
+func (rs *Ruleset) replacementMaps() []map[string]string {
+	maps := make([]map[string]string, 0, 2*len(sourceScripts))
+	for _, script := range sourceScripts {
+		conjunctions, letters := script.maps(rs)
+		maps = append(maps, *conjunctions, *letters)
+	}
+	return maps
+}
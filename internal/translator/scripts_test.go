@@ -0,0 +1,65 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzScriptRoundTrip tests that Latin, Cyrillic and Greek text, mixed or not, round-trips
+func FuzzScriptRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Привет, мир! Что нового?")
+	f.Add("Сәлем, әлем! Қазақ тілі және ағылшын тілі")
+	f.Add("Γεια σου κόσμε! Το ΜΠΑΡ και ο ΝΤΟΜΑΤΑ ϊϋ")
+	f.Add("hello Привет γεια 42 shell чтоthe")
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+
+		translated := applyMapReplacementsToPejelagarto(input)
+		if reversed := applyMapReplacementsFromPejelagarto(translated); reversed != input {
+			t.Errorf("map replacements failed\nInput:      %q\nTranslated: %q\nReversed:   %q", input, translated, reversed)
+		}
+
+		checkRoundTrip(t, New(Options{DisableTimestamp: true}), input)
+	})
+}
+
+// TestScriptMaps verifies every script keeps the constraints of the Latin maps
+func TestScriptMaps(t *testing.T) {
+	rs := DefaultRuleset()
+	for _, script := range sourceScripts {
+		conjunctionMap, letterMap := script.maps(rs)
+		for key, value := range *letterMap {
+			if script.isVowel(key) != script.isVowel(value) {
+				t.Errorf("%s letter %q maps to %q, mixing vowels and consonants", script.name, key, value)
+			}
+		}
+		if script.name == "Latin" {
+			// The built-in Latin conjunctions predate the rule and keep their historical values
+			continue
+		}
+		for key, value := range *conjunctionMap {
+			if strings.ContainsAny(value, strings.Join(sortedKeys(*letterMap), "")) {
+				t.Errorf("%s conjunction %q maps to %q, which uses letters of the letter map", script.name, key, value)
+			}
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Привет мир", "'Щъцьхж нел"},
+		{"Сәлем", "'Шьчъж"},
+		{"Γεια μου", "'Ψχζξ ν'ψζ"},
+		{"hello мир γεια", "'araka нел 'ψχζξ"},
+	}
+	for _, tt := range tests {
+		if got := applyMapReplacementsToPejelagarto(tt.input); got != tt.want {
+			t.Errorf("applyMapReplacementsToPejelagarto(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
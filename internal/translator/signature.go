@@ -0,0 +1,153 @@
+package translator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"strings"
+)
+
+// Message authentication
+// With Options.Key, the encoder appends SignatureMarker and the HMAC-SHA256 tag, truncated to
+// signatureTagBytes, as hexadecimal digits from SignatureDigitIndex, most significant first
+// They are hidden after the timestamp characters and in order, like the version 2 digits
+// The tag covers the hidden timestamp as the decoder restores it, the Human text without it and,
+// when there are some, the markers hidden after the tag in their order (the normalization, locality
+// and lossless markers, the ruleset version and the metadata), so none can be added or removed:
+//
+//	HMAC(key, timestamp + "\n" + human)
+//	HMAC(key, timestamp + "\n" + markers + "\n" + human)
+
+// SignatureMarker announces the authentication tag
+var SignatureMarker = "\u23D3"
+
+// SignatureDigitIndex holds the 16 digits of the authentication tag
+var SignatureDigitIndex = []string{
+	"\u23D4", "\u23D5", "\u23D6", "\u23D7", "\u23D8", "\u23D9", "\u23DA", "\u23DB",
+	"\u23DC", "\u23DD", "\u23DE", "\u23DF", "\u23E0", "\u23E1", "\u23E2", "\u23E3",
+}
+
+// signatureTagBytes is the length of the truncated HMAC tag
+const signatureTagBytes = 16
+
+// Verification is the outcome of checking the authentication tag of a text
+type Verification string
+
+const (
+	// VerificationAuthentic means the tag matches the text, timestamp and key
+	VerificationAuthentic Verification = "authentic"
+	// VerificationTampered means the tag is damaged or does not match: the text, the timestamp or the key differ
+	VerificationTampered Verification = "tampered"
+	// VerificationUnsigned means the text carries no tag
+	VerificationUnsigned Verification = "unsigned"
+)
+
+// Verify checks the authentication tag of Pejelagarto text produced with the default options and key
+func Verify(input string, key []byte) Verification {
+	return New(Options{Key: key}).Verify(input)
+}
+
+// Verify checks the authentication tag of Pejelagarto text produced with the translator's options
+func (t *Translator) Verify(input string) Verification {
+	_, verification, _ := t.FromPejelagartoVerifiedContext(context.Background(), input)
+	return verification
+}
+
+// FromPejelagartoVerifiedContext translates Pejelagarto text back to Human and checks its authentication tag
+// Texts are always unsigned when the timestamp stage is disabled
+func (t *Translator) FromPejelagartoVerifiedContext(ctx context.Context, input string) (string, Verification, error) {
+	d, err := t.decode(ctx, input, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	verification := VerificationUnsigned
+	if !t.opts.DisableTimestamp {
+		hidden, _, _ := splitHiddenTimestampChars(input)
+		if tag, signed := readSignature(hidden); signed {
+			verification = VerificationTampered
+			if tag != nil && hmac.Equal(tag, signatureTag(t.opts.Key, d.human, d.timestamp, hiddenMarkers(hidden))) {
+				verification = VerificationAuthentic
+			}
+		}
+	}
+	return t.finishDecode(d), verification, nil
+}
+
+// signatureTag computes the truncated HMAC of the Human text, the restored timestamp and the hidden markers
+func signatureTag(key []byte, human string, timestamp string, markers string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	if markers != "" {
+		mac.Write([]byte(markers))
+		mac.Write([]byte("\n"))
+	}
+	mac.Write([]byte(human))
+	return mac.Sum(nil)[:signatureTagBytes]
+}
+
+// signatureChars returns the characters hiding the tag of human, the given timestamp characters and
+// the markers hidden after the tag
+func signatureChars(key []byte, human string, timestampChars []string, markers []string) []string {
+	timestamp := readTimestampUsingSpecialCharEncoding(strings.Join(timestampChars, ""))
+	chars := []string{SignatureMarker}
+	for _, b := range signatureTag(key, human, timestamp, strings.Join(markers, "")) {
+		chars = appendSignatureDigits(chars, b)
+	}
+	return chars
+}
+
+// hiddenMarkers returns the hidden characters the encoder writes after the tag, in their order
+// They are the components listed after the signature digits in timestampComponents
+func hiddenMarkers(hidden string) string {
+	specialChars := timestampSpecialChars()
+	first := specialChars[[]rune(NormalizationMarker)[0]]
+	var markers strings.Builder
+	for _, r := range hidden {
+		if component, isSpecial := specialChars[r]; isSpecial && component >= first {
+			markers.WriteRune(r)
+		}
+	}
+	return markers.String()
+}
+
+// appendSignatureDigits appends one byte of the tag as two digits
+func appendSignatureDigits(chars []string, b byte) []string {
+	return append(chars, SignatureDigitIndex[b>>4], SignatureDigitIndex[b&0x0F])
+}
+
+// readSignature reads the tag hidden in input
+// signed is true when a marker is present; tag is nil when the marker or digits are not those written by the encoder
+func readSignature(input string) (tag []byte, signed bool) {
+	if strings.Count(input, SignatureMarker) == 0 {
+		return nil, false
+	}
+	if strings.Count(input, SignatureMarker) > 1 {
+		return nil, true
+	}
+
+	digitValues := make(map[rune]byte, len(SignatureDigitIndex))
+	for i, digit := range SignatureDigitIndex {
+		digitValues[[]rune(digit)[0]] = byte(i)
+	}
+	markerAt := strings.Index(input, SignatureMarker)
+	var digits []byte
+	for i, r := range input {
+		if value, isDigit := digitValues[r]; isDigit {
+			if i < markerAt {
+				return nil, true
+			}
+			digits = append(digits, value)
+		}
+	}
+	if len(digits) != 2*signatureTagBytes {
+		return nil, true
+	}
+
+	tag = make([]byte, signatureTagBytes)
+	for i := range tag {
+		tag[i] = digits[2*i]<<4 | digits[2*i+1]
+	}
+	return tag, true
+}
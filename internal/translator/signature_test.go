@@ -0,0 +1,92 @@
+package translator
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// FuzzSignedRoundTrip tests that signed text verifies with its key and translates back like unsigned text
+func FuzzSignedRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("", "key")
+	f.Add("Hello, World! 123\n2025-10-19T14:30:45+05:30", "shared secret")
+	f.Fuzz(func(t *testing.T, input string, key string) {
+		if !utf8.ValidString(input) || key == "" {
+			return
+		}
+
+		signer := fixedTestTranslator(Options{Key: []byte(key)})
+		unsigned := fixedTestTranslator(Options{})
+
+		signed := signer.ToPejelagarto(input)
+		if verification := signer.Verify(signed); verification != VerificationAuthentic {
+			t.Fatalf("Verify() = %s, want authentic\nInput:  %q\nSigned: %q", verification, input, signed)
+		}
+		if got, want := unsigned.FromPejelagarto(signed), unsigned.FromPejelagarto(unsigned.ToPejelagarto(input)); got != want {
+			t.Errorf("signed text translates back differently\nSigned:   %q\nUnsigned: %q", got, want)
+		}
+		if verification := Verify(signed, []byte(key+"x")); verification != VerificationTampered {
+			t.Errorf("Verify() with another key = %s, want tampered", verification)
+		}
+	})
+}
+
+// TestVerify verifies altered, re-timed and unsigned texts are told apart
+func TestVerify(t *testing.T) {
+	key := []byte("shared secret")
+	signer := New(Options{Timestamp: time.Date(2025, time.October, 19, 14, 30, 45, 0, time.UTC), Rand: SeededRand(3), Key: key})
+	signed := signer.ToPejelagarto("meet at the north gate at noon")
+
+	tests := []struct {
+		name  string
+		input string
+		want  Verification
+	}{
+		{"authentic", signed, VerificationAuthentic},
+		{"unsigned", TranslateToPejelagarto("meet at the north gate at noon"), VerificationUnsigned},
+		{"altered text", strings.Replace(signed, " ", "  ", 1), VerificationTampered},
+		{"altered timestamp", strings.Replace(signed, MonthSpecialCharIndex[9], MonthSpecialCharIndex[10], 1), VerificationTampered},
+		{"extra tag digit", signed + SignatureDigitIndex[0], VerificationTampered},
+		{"second marker", signed + SignatureMarker, VerificationTampered},
+		{"added normalization marker", signed + NormalizationMarker, VerificationTampered},
+		{"added locality marker", signed + SentenceLocalityMarker, VerificationTampered},
+		{"added lossless marker", signed + LosslessMarker, VerificationTampered},
+		{"removed ruleset version", withoutRunes(signed, RulesetVersionMarker), VerificationTampered},
+	}
+	for _, tt := range tests {
+		if got := Verify(tt.input, key); got != tt.want {
+			t.Errorf("%s: Verify() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// Removing a marker the text was signed with is detected too
+	decomposed := signer.ToPejelagarto(norm.NFD.String("café au lait"))
+	if got := Verify(decomposed, key); got != VerificationAuthentic {
+		t.Errorf("Verify(NFD) = %s, want %s", got, VerificationAuthentic)
+	}
+	if got := Verify(withoutRunes(decomposed, NormalizationMarker), key); got != VerificationTampered {
+		t.Errorf("Verify(NFD without its marker) = %s, want %s", got, VerificationTampered)
+	}
+
+	text, verification, err := signer.FromPejelagartoVerifiedContext(t.Context(), signed)
+	if err != nil || verification != VerificationAuthentic {
+		t.Fatalf("FromPejelagartoVerifiedContext() = %s, %v", verification, err)
+	}
+	if want := "meet at the north gate at noon\n2025-10-19T14:30:45Z"; text != want {
+		t.Errorf("FromPejelagartoVerifiedContext() text = %q, want %q", text, want)
+	}
+}
+
+// withoutRunes removes every rune of chars from text
+func withoutRunes(text, chars string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,324 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Custom stages
+// A Stage is a reversible transform registered with RegisterStage, e.g. a vowel rotation or a
+// ROT-style stage, and added to the pipeline of the translators naming it in Options.Stages. It runs
+// right after the built-in stage it is placed after, and its inverse runs right before that stage's
+// in the decoder, so any transform that gives back its input composes with the others
+// Their names are hidden with the ruleset version, and a text written with other stages than the
+// decoder's fails with ErrStageMismatch
+// RegisterStage first round-trips the stage over a fixed corpus and random text, as the fuzz tests of
+// the built-in stages do, and refuses it if a text does not come back. It also refuses stages that add
+// timestamp characters, which the decoder removes, or change the delimiters of protected spans
+
+// Stage is a reversible transform of the translation pipeline
+// Forward and Inverse must be safe for concurrent use
+type Stage interface {
+	// Name identifies the stage in the registry and in errors
+	Name() string
+	// Forward transforms text on its way to Pejelagarto
+	Forward(string) string
+	// Inverse gives back the input of Forward
+	Inverse(string) string
+}
+
+// funcStage is a Stage made of two functions
+type funcStage struct {
+	name             string
+	forward, inverse func(string) string
+}
+
+func (s funcStage) Name() string                { return s.name }
+func (s funcStage) Forward(input string) string { return s.forward(input) }
+func (s funcStage) Inverse(input string) string { return s.inverse(input) }
+
+// NewStage returns a Stage from its forward and inverse functions
+func NewStage(name string, forward, inverse func(string) string) Stage {
+	return funcStage{name: name, forward: forward, inverse: inverse}
+}
+
+// ErrStageMismatch is returned when a text was written with other custom stages than the translator's
+var ErrStageMismatch = errors.New("custom stages differ from those the text was written with")
+
+// StagePositions lists the built-in stages a custom stage can be placed after, in encoding order
+// The empty position places it first, right after the contents of protected spans are taken out
+var StagePositions = []string{"", "numbers", "punctuation", "replacements", "accents", "consonants", "case"}
+
+// StageError explains why RegisterStage refused a stage
+type StageError struct {
+	Stage  string
+	Input  string // the text that failed the round-trip check, empty for other reasons
+	Reason string
+}
+
+func (e *StageError) Error() string {
+	if e.Input == "" {
+		return fmt.Sprintf("stage %q refused: %s", e.Stage, e.Reason)
+	}
+	return fmt.Sprintf("stage %q refused: %s, for input %q", e.Stage, e.Reason, e.Input)
+}
+
+// placedStage is a registered stage and the built-in stage it follows
+type placedStage struct {
+	stage Stage
+	after string
+}
+
+// stageRegistry holds the registered stages in registration order
+var stageRegistry struct {
+	mu     sync.RWMutex
+	stages []placedStage
+}
+
+// RegisterStage checks a stage and makes it available to Options.Stages, placed after the built-in
+// stage named after (see StagePositions); stages placed at the same position run in the order of Options.Stages
+// It is meant to be called at startup, a refused stage returns a *StageError
+func RegisterStage(stage Stage, after string) error {
+	name := stage.Name()
+	if name == "" {
+		return &StageError{Stage: name, Reason: "stages must have a name"}
+	}
+	if strings.Contains(name, ",") {
+		return &StageError{Stage: name, Reason: "names are hidden joined with commas and cannot hold one"}
+	}
+	if !containsString(StagePositions, after) {
+		return &StageError{Stage: name, Reason: fmt.Sprintf("unknown position %q, expected one of %q", after, StagePositions)}
+	}
+	if containsString(StagePositions, name) {
+		return &StageError{Stage: name, Reason: "the name of a built-in stage"}
+	}
+	if err := CheckStage(stage); err != nil {
+		return err
+	}
+
+	stageRegistry.mu.Lock()
+	defer stageRegistry.mu.Unlock()
+	for _, placed := range stageRegistry.stages {
+		if placed.stage.Name() == name {
+			return &StageError{Stage: name, Reason: "a stage with this name is already registered"}
+		}
+	}
+	stageRegistry.stages = append(stageRegistry.stages, placedStage{stage: stage, after: after})
+	return nil
+}
+
+// UnregisterStage removes the registered stage with the given name, reporting whether there was one
+func UnregisterStage(name string) bool {
+	stageRegistry.mu.Lock()
+	defer stageRegistry.mu.Unlock()
+	for i, placed := range stageRegistry.stages {
+		if placed.stage.Name() == name {
+			stageRegistry.stages = append(stageRegistry.stages[:i:i], stageRegistry.stages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RegisteredStages returns the names of the registered stages in registration order
+func RegisteredStages() []string {
+	stageRegistry.mu.RLock()
+	defer stageRegistry.mu.RUnlock()
+	names := make([]string, len(stageRegistry.stages))
+	for i, placed := range stageRegistry.stages {
+		names[i] = placed.stage.Name()
+	}
+	return names
+}
+
+// lookupStages returns the registered stages named by Options.Stages, in that order
+func lookupStages(names []string) ([]placedStage, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	stageRegistry.mu.RLock()
+	defer stageRegistry.mu.RUnlock()
+	stages := make([]placedStage, 0, len(names))
+	for i, name := range names {
+		if containsString(names[:i], name) {
+			return nil, fmt.Errorf("option stages: %q is listed twice", name)
+		}
+		found := false
+		for _, placed := range stageRegistry.stages {
+			if placed.stage.Name() == name {
+				stages, found = append(stages, placed), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("option stages: no stage registered as %q", name)
+		}
+	}
+	return stages, nil
+}
+
+// stageNames returns the names of the custom stages of the translator
+func (t *Translator) stageNames() []string {
+	names := make([]string, len(t.stages))
+	for i, placed := range t.stages {
+		names[i] = placed.stage.Name()
+	}
+	return names
+}
+
+// checkTextStages returns an error wrapping ErrStageMismatch when the hidden characters of a text
+// name other custom stages than the translator's
+// Texts that hide nothing, or a damaged version, do not tell and are not checked
+func (t *Translator) checkTextStages(input string) error {
+	if t.opts.DisableTimestamp {
+		return nil
+	}
+	hidden, _, _ := splitHiddenTimestampChars(input)
+	if RemoveTimestampSpecialCharacters(hidden) == hidden {
+		return nil
+	}
+	var stages []string
+	if strings.Contains(hidden, RulesetVersionMarker) {
+		var ok bool
+		if _, stages, ok = readHiddenVersion(hidden); !ok {
+			return nil
+		}
+	}
+	if names := t.stageNames(); !slices.Equal(stages, names) {
+		return fmt.Errorf("%w: written with %q, the translator has %q", ErrStageMismatch, stages, names)
+	}
+	return nil
+}
+
+// withStages inserts the custom stages into the steps of the encoder, after the step they follow,
+// or into those of the decoder, inverted and before it
+// The empty position follows the step named "protect" in the encoder and precedes "restore" in the decoder
+func withStages(steps []pipelineStep, stages []placedStage, decoder bool) []pipelineStep {
+	if len(stages) == 0 {
+		return steps
+	}
+
+	result := make([]pipelineStep, 0, len(steps)+len(stages))
+	for _, step := range steps {
+		position, isPosition := step.name, step.name != ""
+		if step.name == "protect" || step.name == "restore" {
+			position, isPosition = "", decoder == (step.name == "restore")
+		}
+		if isPosition && decoder {
+			// Inverses run in the reverse order of their stages
+			for i := len(stages) - 1; i >= 0; i-- {
+				if stages[i].after == position {
+					result = append(result, pipelineStep{apply: stages[i].stage.Inverse})
+				}
+			}
+		}
+		result = append(result, step)
+		if isPosition && !decoder {
+			for _, s := range stages {
+				if s.after == position {
+					result = append(result, pipelineStep{apply: s.stage.Forward})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// stageCheckCorpus is round-tripped by every stage before it is registered
+var stageCheckCorpus = []string{
+	"",
+	"Hello, World! How are you?",
+	"The QUICK brown fox jumps over the lazy dog.\nSecond line\r\n\tTabbed",
+	"café naïve résumé Ångström ŒUVRE straße İstanbul",
+	"e\u0301 a\u0308 o\u031B\u0301 y\u0328",
+	"Привет, мир! Сәлем Γεια σου κόσμε",
+	"123 -3.14 $1,299.99 6.02e+23 ١٢٣ ４２",
+	"'quoted' \u00AD escaped \u00AD\u00AD' it's",
+	"⟦protected⟧ text ⟦nested ⟦ spans⟧⟧",
+	"日本語 中文 한국어 🦎🎉 👨\u200D👩\u200D👧",
+	"\u3164\uE000\uE0FF",
+}
+
+const (
+	stageCheckRounds   = 1000 // random texts round-tripped by CheckStage
+	stageCheckMaxRunes = 64
+)
+
+// stageCheckRunes are drawn often by the random texts, the rest are any valid rune
+var stageCheckRunes = []rune("aeiouyAEIOUYbcdfghjklmnpqrstvwxzBCDFGHJKLMNPQRSTVWXZ0123456789 .,;:!?'\"-\n\u00AD\u0301\u0308éÉñçßаяЖωΣ⟦⟧🦎")
+
+// CheckStage round-trips a stage over a fixed corpus and random text without registering it
+// It returns a *StageError for the first text that fails
+func CheckStage(stage Stage) (err error) {
+	name := stage.Name()
+	var input string
+	defer func() {
+		if r := recover(); r != nil {
+			err = &StageError{Stage: name, Input: input, Reason: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < len(stageCheckCorpus)+stageCheckRounds; i++ {
+		if i < len(stageCheckCorpus) {
+			input = stageCheckCorpus[i]
+		} else {
+			input = randomStageInput(rng)
+		}
+		if reason := checkStageRoundTrip(stage, input); reason != "" {
+			return &StageError{Stage: name, Input: input, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// checkStageRoundTrip returns why a stage fails on input, or "" if it gives it back
+func checkStageRoundTrip(stage Stage, input string) string {
+	output := stage.Forward(input)
+	if reversed := stage.Inverse(output); reversed != input {
+		return fmt.Sprintf("Inverse(Forward(input)) = %q, from %q", reversed, output)
+	}
+	if RemoveTimestampSpecialCharacters(input) == input && RemoveTimestampSpecialCharacters(output) != output {
+		return fmt.Sprintf("output %q has timestamp characters, which the decoder removes", output)
+	}
+	for _, delimiter := range []string{ProtectOpen, ProtectClose} {
+		if strings.Count(output, delimiter) != strings.Count(input, delimiter) {
+			return fmt.Sprintf("output %q changes the protected span delimiter %q", output, delimiter)
+		}
+	}
+	return ""
+}
+
+// randomStageInput returns a random text mixing common letters, marks and escapes with any rune
+func randomStageInput(rng *rand.Rand) string {
+	runes := make([]rune, rng.Intn(stageCheckMaxRunes+1))
+	for i := range runes {
+		if rng.Intn(4) > 0 {
+			runes[i] = stageCheckRunes[rng.Intn(len(stageCheckRunes))]
+			continue
+		}
+		for {
+			r := rune(rng.Intn(utf8.MaxRune + 1))
+			if utf8.ValidRune(r) {
+				runes[i] = r
+				break
+			}
+		}
+	}
+	return string(runes)
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package translator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// rot13 rotates the Latin letters by 13, its own inverse
+func rot13(input string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'a' + (r-'a'+13)%26
+		case r >= 'A' && r <= 'Z':
+			return 'A' + (r-'A'+13)%26
+		}
+		return r
+	}, input)
+}
+
+// rotateVowels moves every lowercase vowel to the next one, or to the previous one when back is set
+func rotateVowels(back bool) func(string) string {
+	vowels := []rune("aeiou")
+	return func(input string) string {
+		return strings.Map(func(r rune) rune {
+			for i, vowel := range vowels {
+				if r == vowel {
+					if back {
+						return vowels[(i+len(vowels)-1)%len(vowels)]
+					}
+					return vowels[(i+1)%len(vowels)]
+				}
+			}
+			return r
+		}, input)
+	}
+}
+
+// swapBrackets swaps the opening delimiter of protected spans and '[', its own inverse
+var swapBrackets = strings.NewReplacer(ProtectOpen, "[", "[", ProtectOpen)
+
+// FuzzCustomStageRoundTrip tests that custom stages round-trip at every position
+func FuzzCustomStageRoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Hello world, how are you? 3.14 'quoted' ⟦Protected⟧")
+	f.Add("Привет мир\nCafé AEIOU aeiou")
+	var names []string
+	for i, position := range StagePositions {
+		name := "rot13-" + position
+		if i%2 == 1 {
+			name = "vowels-" + position
+		}
+		stage := NewStage(name, rot13, rot13)
+		if i%2 == 1 {
+			stage = NewStage(name, rotateVowels(false), rotateVowels(true))
+		}
+		if err := RegisterStage(stage, position); err != nil {
+			f.Fatalf("RegisterStage(%q, %q): %v", name, position, err)
+		}
+		f.Cleanup(func() { UnregisterStage(name) })
+		names = append(names, name)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+		checkRoundTrip(t, fixedTestTranslator(Options{Stages: names}), input)
+	})
+}
+
+// TestRegisterStage verifies stages only change the output of the translators naming them and broken ones are refused
+func TestRegisterStage(t *testing.T) {
+	tr := New(Options{DisableTimestamp: true})
+	input := "Hello world"
+	before := tr.ToPejelagarto(input)
+
+	if err := RegisterStage(NewStage("rot13", rot13, rot13), "replacements"); err != nil {
+		t.Fatalf("RegisterStage: %v", err)
+	}
+	t.Cleanup(func() { UnregisterStage("rot13") })
+	if got := RegisteredStages(); len(got) != 1 || got[0] != "rot13" {
+		t.Errorf("RegisteredStages() = %q, want [rot13]", got)
+	}
+	if got := tr.ToPejelagarto(input); got != before {
+		t.Errorf("the stage changed the output of a translator without it: %q, want %q", got, before)
+	}
+	staged := New(Options{DisableTimestamp: true, Stages: []string{"rot13"}})
+	pejelagarto := staged.ToPejelagarto(input)
+	if pejelagarto == before {
+		t.Errorf("the stage did not change the output %q", pejelagarto)
+	}
+	if reversed := staged.FromPejelagarto(pejelagarto); reversed != input {
+		t.Errorf("round-trip = %q, want %q", reversed, input)
+	}
+	for _, stages := range [][]string{{"rot14"}, {"rot13", "rot13"}} {
+		if err := New(Options{Stages: stages}).Err(); err == nil {
+			t.Errorf("New(Stages: %q) succeeded", stages)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		stage  Stage
+		after  string
+		reason string
+	}{
+		{"lossy", NewStage("lower", strings.ToLower, strings.ToLower), "", "Inverse(Forward(input))"},
+		{"timestamp characters", NewStage("clock", func(s string) string { return s + DaySpecialCharIndex[0] }, func(s string) string { return strings.TrimSuffix(s, DaySpecialCharIndex[0]) }), "", "timestamp characters"},
+		{"delimiters", NewStage("brackets", swapBrackets.Replace, swapBrackets.Replace), "", "delimiter"},
+		{"panic", NewStage("panic", func(s string) string { panic("boom") }, func(s string) string { return s }), "", "panic: boom"},
+		{"unknown position", NewStage("rot13b", rot13, rot13), "timestamp", "unknown position"},
+		{"built-in name", NewStage("accents", rot13, rot13), "", "built-in"},
+		{"duplicate", NewStage("rot13", rot13, rot13), "case", "already registered"},
+		{"comma", NewStage("rot,13", rot13, rot13), "", "commas"},
+	}
+	for _, tt := range tests {
+		err := RegisterStage(tt.stage, tt.after)
+		var stageErr *StageError
+		if !errors.As(err, &stageErr) || !strings.Contains(stageErr.Reason, tt.reason) {
+			t.Errorf("%s: RegisterStage error = %v, want a reason with %q", tt.name, err, tt.reason)
+		}
+	}
+	if got := RegisteredStages(); len(got) != 1 {
+		t.Errorf("refused stages were registered: %q", got)
+	}
+}
+
+// TestStageMismatch verifies texts name their custom stages and decoders with other stages refuse them
+func TestStageMismatch(t *testing.T) {
+	if err := RegisterStage(NewStage("rot13", rot13, rot13), "replacements"); err != nil {
+		t.Fatalf("RegisterStage: %v", err)
+	}
+	t.Cleanup(func() { UnregisterStage("rot13") })
+
+	input := "Hello world"
+	staged := fixedTestTranslator(Options{Stages: []string{"rot13"}})
+	plain := fixedTestTranslator(Options{})
+	private := fixedTestTranslator(Options{Stages: []string{"rot13"}, Ruleset: generatedRuleset(t, "our little secret")})
+	tests := []struct {
+		name     string
+		writer   *Translator
+		reader   *Translator
+		mismatch bool
+	}{
+		{"same stages", staged, staged, false},
+		{"stages unknown to the reader", staged, plain, true},
+		{"stages the text does not have", plain, staged, true},
+		{"private dialect", private, private, false},
+		{"private dialect read without its stages", private, fixedTestTranslator(Options{Ruleset: generatedRuleset(t, "our little secret")}), true},
+	}
+	for _, tt := range tests {
+		pejelagarto := tt.writer.ToPejelagarto(input)
+		_, err := tt.reader.FromPejelagartoContext(t.Context(), pejelagarto)
+		if got := errors.Is(err, ErrStageMismatch); got != tt.mismatch {
+			t.Errorf("%s: FromPejelagartoContext error = %v, want mismatch %v", tt.name, err, tt.mismatch)
+		}
+		diagnosed := false
+		for _, diagnostic := range tt.reader.Inspect(pejelagarto) {
+			diagnosed = diagnosed || diagnostic.Code == DiagnosticStages
+		}
+		if diagnosed != tt.mismatch {
+			t.Errorf("%s: Inspect reported the stages %v, want %v", tt.name, diagnosed, tt.mismatch)
+		}
+	}
+
+	// The private dialect hides the stages, not its fingerprint
+	hidden, _, _ := splitHiddenTimestampChars(private.ToPejelagarto(input))
+	if fingerprint, stages, ok := readHiddenVersion(hidden); !ok || fingerprint != privateRulesetVersion || len(stages) != 1 || stages[0] != "rot13" {
+		t.Errorf("readHiddenVersion() = %q, %q, %v, want %q and [rot13]", fingerprint, stages, ok, privateRulesetVersion)
+	}
+	// Without the timestamp stage nothing is hidden and nothing is checked
+	untimed := New(Options{DisableTimestamp: true, Stages: []string{"rot13"}})
+	if _, err := New(Options{DisableTimestamp: true}).FromPejelagartoContext(t.Context(), untimed.ToPejelagarto(input)); err != nil {
+		t.Errorf("FromPejelagartoContext() without timestamps = %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package translator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Streaming translation
+// Large documents are split into chunks of Human text that are translated independently
+// Each translated chunk is written as a self-describing frame:
+//
+//	frameStart | version | flag | payload length in bytes (decimal) | frameHeaderEnd | payload
+//
+// The flag records whether the chunk ended with an ISO 8601 timestamp line, so the decoder
+// knows whether the timestamp line added by TranslateFromPejelagarto belongs to the text
+// Memory use is bounded by the chunk size on encoding and by MaxStreamFrameSize on decoding
+
+const (
+	// DefaultStreamChunkSize is the approximate number of bytes of Human text per frame
+	DefaultStreamChunkSize = 64 * 1024
+	// MaxStreamFrameSize is the largest frame payload the decoder accepts
+	MaxStreamFrameSize = 64 * 1024 * 1024
+
+	frameStart         = '\u001E' // Record separator - starts every frame
+	frameHeaderEnd     = '\u001F' // Unit separator - ends the frame header
+	frameVersion       = '1'
+	frameFlagTimestamp = 't' // chunk ended with its own ISO 8601 timestamp line
+	frameFlagNone      = 'n'
+	maxFrameHeaderSize = 32
+)
+
+// ErrInvalidStream is returned when the decoder input is not a sequence of Pejelagarto frames
+var ErrInvalidStream = errors.New("invalid Pejelagarto stream")
+
+// StreamEncoder translates Human text written to it into framed Pejelagarto
+// Call Close to flush the last chunk
+type StreamEncoder struct {
+	translator *Translator
+	w          io.Writer
+	buf        []byte
+	chunkSize  int
+}
+
+// NewStreamEncoder returns an encoder writing frames to w using the default options
+// chunkSize <= 0 selects DefaultStreamChunkSize
+func NewStreamEncoder(w io.Writer, chunkSize int) *StreamEncoder {
+	return defaultTranslator.NewStreamEncoder(w, chunkSize)
+}
+
+// NewStreamEncoder returns an encoder writing frames to w using the translator's options
+// chunkSize <= 0 selects DefaultStreamChunkSize
+func (t *Translator) NewStreamEncoder(w io.Writer, chunkSize int) *StreamEncoder {
+	if chunkSize <= 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	if chunkSize < utf8.UTFMax {
+		chunkSize = utf8.UTFMax // a chunk must be able to hold any single rune
+	}
+	return &StreamEncoder{translator: t, w: w, chunkSize: chunkSize}
+}
+
+// Write buffers Human text and emits a frame for every complete chunk
+func (e *StreamEncoder) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	// Wait for at least one byte past the chunk so an incomplete trailing rune is never cut off
+	for len(e.buf) > e.chunkSize {
+		cut := chunkBoundary(e.buf, e.chunkSize)
+		if err := e.writeFrame(e.buf[:cut]); err != nil {
+			return len(p), err
+		}
+		e.buf = append(e.buf[:0], e.buf[cut:]...)
+	}
+	return len(p), nil
+}
+
+// Close flushes any buffered text as a final frame
+func (e *StreamEncoder) Close() error {
+	if len(e.buf) == 0 {
+		return nil
+	}
+	err := e.writeFrame(e.buf)
+	e.buf = e.buf[:0]
+	return err
+}
+
+// writeFrame translates one chunk and writes it with its frame header
+func (e *StreamEncoder) writeFrame(chunk []byte) error {
+	flag := byte(frameFlagNone)
+	if _, timestamp := removeISO8601timestamp(string(chunk)); timestamp != "" {
+		flag = frameFlagTimestamp
+	}
+
+	payload := e.translator.ToPejelagarto(string(chunk))
+	header := make([]byte, 0, maxFrameHeaderSize)
+	header = append(header, frameStart, frameVersion, flag)
+	header = strconv.AppendInt(header, int64(len(payload)), 10)
+	header = append(header, frameHeaderEnd)
+
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, payload)
+	return err
+}
+
+// chunkBoundary picks where to cut the buffer: after the last line break within the chunk size,
+// or else at the last rune boundary so that no UTF-8 sequence is split
+func chunkBoundary(buf []byte, chunkSize int) int {
+	for i := chunkSize - 1; i >= 0; i-- {
+		if buf[i] == '\n' {
+			return i + 1
+		}
+	}
+	for i := chunkSize; i > chunkSize-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			return i
+		}
+	}
+	return chunkSize
+}
+
+// StreamDecoder reads framed Pejelagarto and returns the Human text through Read
+type StreamDecoder struct {
+	translator *Translator
+	r          *bufio.Reader
+	pending    []byte
+}
+
+// NewStreamDecoder returns a decoder reading frames from r using the default options
+func NewStreamDecoder(r io.Reader) *StreamDecoder {
+	return defaultTranslator.NewStreamDecoder(r)
+}
+
+// NewStreamDecoder returns a decoder reading frames from r using the translator's options
+func (t *Translator) NewStreamDecoder(r io.Reader) *StreamDecoder {
+	return &StreamDecoder{translator: t, r: bufio.NewReader(r)}
+}
+
+// Read decodes frames as needed to fill p
+func (d *StreamDecoder) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		human, err := d.nextFrame()
+		if err != nil {
+			return 0, err
+		}
+		d.pending = []byte(human)
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// nextFrame reads and translates a single frame, returning io.EOF at a clean end of stream
+func (d *StreamDecoder) nextFrame() (string, error) {
+	start, err := d.r.ReadByte()
+	if err == io.EOF {
+		return "", io.EOF
+	}
+	if err != nil {
+		return "", err
+	}
+	if start != frameStart {
+		return "", fmt.Errorf("%w: expected frame start, got %q", ErrInvalidStream, start)
+	}
+
+	header := make([]byte, 0, maxFrameHeaderSize)
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return "", fmt.Errorf("%w: truncated frame header", ErrInvalidStream)
+		}
+		if b == frameHeaderEnd {
+			break
+		}
+		if len(header) == maxFrameHeaderSize {
+			return "", fmt.Errorf("%w: frame header too long", ErrInvalidStream)
+		}
+		header = append(header, b)
+	}
+
+	if len(header) < 3 || header[0] != frameVersion {
+		return "", fmt.Errorf("%w: unsupported frame header %q", ErrInvalidStream, header)
+	}
+	flag := header[1]
+	if flag != frameFlagTimestamp && flag != frameFlagNone {
+		return "", fmt.Errorf("%w: unknown frame flag %q", ErrInvalidStream, flag)
+	}
+	size, err := strconv.Atoi(string(header[2:]))
+	if err != nil || size < 0 || size > MaxStreamFrameSize {
+		return "", fmt.Errorf("%w: invalid frame length %q", ErrInvalidStream, header[2:])
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(d.r, payload); err != nil {
+		return "", fmt.Errorf("%w: truncated frame payload", ErrInvalidStream)
+	}
+
+	human := d.translator.FromPejelagarto(string(payload))
+	if flag == frameFlagNone {
+		// The chunk had no timestamp line of its own, drop the one reconstructed from the special characters
+		human, _ = removeISO8601timestamp(human)
+	}
+	return human, nil
+}
+
+// TranslateStreamToPejelagarto translates Human text from r into framed Pejelagarto written to w
+func TranslateStreamToPejelagarto(w io.Writer, r io.Reader, chunkSize int) error {
+	return defaultTranslator.StreamToPejelagarto(w, r, chunkSize)
+}
+
+// TranslateStreamFromPejelagarto translates framed Pejelagarto from r into Human text written to w
+func TranslateStreamFromPejelagarto(w io.Writer, r io.Reader) error {
+	return defaultTranslator.StreamFromPejelagarto(w, r)
+}
+
+// StreamToPejelagarto translates Human text from r into framed Pejelagarto written to w
+func (t *Translator) StreamToPejelagarto(w io.Writer, r io.Reader, chunkSize int) error {
+	encoder := t.NewStreamEncoder(w, chunkSize)
+	if _, err := io.Copy(encoder, r); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// StreamFromPejelagarto translates framed Pejelagarto from r into Human text written to w
+func (t *Translator) StreamFromPejelagarto(w io.Writer, r io.Reader) error {
+	_, err := io.Copy(w, t.NewStreamDecoder(r))
+	return err
+}
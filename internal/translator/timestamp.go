@@ -0,0 +1,251 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timestamp encoding, version 2
+// Version 1 hides one character each for the UTC day, month, year (2025-2124), hour and minute,
+// so seconds and the offset are lost and other years silently become 2025
+// Version 2 keeps those five characters, so version 1 readers still find the date and minute,
+// and appends TimestampV2Marker followed by a fixed number of digits from TimestampV2DigitIndex:
+//
+//	seconds (2 digits) | offset code (3 digits) | UTC year + 1 (4 digits)
+//
+// Digits are hexadecimal and most significant first. The encoder inserts the special characters
+// in order, so the digits are read in text order
+// The offset code is 0 for "Z", 2880 for "-00:00" and offset minutes + 1440 otherwise
+
+// TimestampV2Marker announces a version 2 timestamp extension
+var TimestampV2Marker = "\u23C1"
+
+// TimestampV2DigitIndex holds the 16 digits of the version 2 timestamp extension
+var TimestampV2DigitIndex = []string{
+	"\u23C2", "\u23C3", "\u23C4", "\u23C5", "\u23C6", "\u23C7", "\u23C8", "\u23C9",
+	"\u23CA", "\u23CB", "\u23CC", "\u23CD", "\u23CE", "\u23D0", "\u23D1", "\u23D2",
+}
+
+const (
+	timestampV2SecondDigits = 2
+	timestampV2OffsetDigits = 3
+	timestampV2YearDigits   = 4
+	timestampV2Digits       = timestampV2SecondDigits + timestampV2OffsetDigits + timestampV2YearDigits
+
+	timestampOffsetUTC          = 0    // "Z"
+	timestampOffsetUnknownLocal = 2880 // "-00:00", RFC 3339 for an unknown local offset
+	timestampOffsetBias         = 1440 // added to the offset in minutes, "+00:00" is 1440
+	timestampYearBias           = 1    // years 0000-9999 in any offset are -1 to 10000 in UTC
+)
+
+// ErrNoTimestamp is returned by ExtractTimestamp when the day, month or year character is missing
+var ErrNoTimestamp = errors.New("no embedded timestamp")
+
+// TimestampMarker is one timestamp character found in a text
+type TimestampMarker struct {
+	Component string `json:"component"` // a timestamp component, "signature marker" or "signature digit"
+	Position  int    `json:"position"`  // rune offset in the text
+}
+
+// EmbeddedTimestamp is the timestamp hidden in a Pejelagarto text
+type EmbeddedTimestamp struct {
+	Time       time.Time         `json:"time"`       // in the original offset for version 2 texts, UTC otherwise
+	Components []string          `json:"components"` // components found, in encoding order
+	Markers    []TimestampMarker `json:"markers"`    // every timestamp character, in text order
+	Tampered   bool              `json:"tampered"`   // several characters for one component, or damaged version 2 digits
+}
+
+// ExtractTimestamp reads the hidden timestamp without translating the text
+// Time is the one TranslateFromPejelagarto would restore; Markers and Tampered are filled in even when
+// it returns ErrNoTimestamp. The escaped characters of lossless texts are part of the Human text
+func ExtractTimestamp(text string) (EmbeddedTimestamp, error) {
+	components := timestampComponents()
+	specialChars := timestampSpecialChars()
+	runes := []rune(text)
+	hidden, _ := hiddenTimestampMask(runes)
+	hiddenChars, _, _ := splitHiddenTimestampChars(text)
+
+	var extracted EmbeddedTimestamp
+	counts := make([]int, len(components))
+	for i, r := range runes {
+		if hidden[i] {
+			component := specialChars[r]
+			counts[component]++
+			extracted.Markers = append(extracted.Markers, TimestampMarker{Component: components[component].name, Position: i})
+		}
+	}
+
+	markers, digits := 0, 0
+	for component, count := range counts {
+		c := components[component]
+		if count == 0 {
+			continue
+		}
+		extracted.Components = append(extracted.Components, c.name)
+		if !c.sequence && count > 1 {
+			extracted.Tampered = true
+		}
+		switch c.name {
+		case "version 2 marker":
+			markers = count
+		case "version 2 digit":
+			digits = count
+		}
+	}
+	if (markers > 0 || digits > 0) && !extracted.Tampered {
+		_, _, _, readable := readTimestampV2Extension(hiddenChars)
+		extracted.Tampered = !readable || digits != timestampV2Digits
+	}
+
+	timestamp := readTimestampUsingSpecialCharEncoding(hiddenChars)
+	if timestamp == "" {
+		return extracted, ErrNoTimestamp
+	}
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		// Only damaged version 2 digits give years RFC 3339 cannot write
+		extracted.Tampered = true
+		return extracted, fmt.Errorf("embedded timestamp %q: %w", timestamp, err)
+	}
+	extracted.Time = parsed
+	return extracted, nil
+}
+
+// timestampSpecialCharTables returns every table of timestamp special characters, including the signature
+// the normalization, locality and lossless markers, the ruleset version and the metadata
+func timestampSpecialCharTables() [][]string {
+	return [][]string{
+		DaySpecialCharIndex, MonthSpecialCharIndex, YearSpecialCharIndex, HourSpecialCharIndex, MinuteSpecialCharIndex,
+		{TimestampV2Marker}, TimestampV2DigitIndex, {SignatureMarker}, SignatureDigitIndex,
+		{NormalizationMarker}, {SentenceLocalityMarker, ParagraphLocalityMarker}, {LosslessMarker},
+		{RulesetVersionMarker}, RulesetVersionDigitIndex, {MetadataMarker}, MetadataDigitIndex,
+	}
+}
+
+// encodedTimestamp is a timestamp split into the fields hidden in the text
+type encodedTimestamp struct {
+	utc        time.Time // the instant; all fields are hidden in UTC
+	offsetCode int       // how the original offset was written, see above
+}
+
+// parseEncodedTimestamp parses an RFC 3339 timestamp, keeping how its offset was written
+func parseEncodedTimestamp(timestamp string) (encodedTimestamp, error) {
+	parsed, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return encodedTimestamp{}, err
+	}
+	encoded := encodedTimestamp{utc: parsed.UTC()}
+	switch {
+	case strings.HasSuffix(timestamp, "Z"):
+		encoded.offsetCode = timestampOffsetUTC
+	case strings.HasSuffix(timestamp, "-00:00"):
+		encoded.offsetCode = timestampOffsetUnknownLocal
+	default:
+		_, offset := parsed.Zone()
+		encoded.offsetCode = offset/60 + timestampOffsetBias
+	}
+	return encoded, nil
+}
+
+// specialChars returns the characters to hide, version 1 characters first
+// Times whose UTC year cannot be written in 4 digits only get the version 1 characters
+func (e encodedTimestamp) specialChars() []string {
+	// Version 1 characters always hold the UTC date and time; years outside the table wrap around
+	yearIndex := (e.utc.Year() - 2025) % len(YearSpecialCharIndex)
+	if yearIndex < 0 {
+		yearIndex += len(YearSpecialCharIndex)
+	}
+	chars := []string{
+		DaySpecialCharIndex[e.utc.Day()-1],
+		MonthSpecialCharIndex[int(e.utc.Month())-1],
+		YearSpecialCharIndex[yearIndex],
+		HourSpecialCharIndex[e.utc.Hour()],
+		MinuteSpecialCharIndex[e.utc.Minute()],
+	}
+	if e.utc.Year() < -timestampYearBias || e.utc.Year() > 9999+timestampYearBias {
+		return chars
+	}
+
+	chars = append(chars, TimestampV2Marker)
+	chars = appendTimestampDigits(chars, e.utc.Second(), timestampV2SecondDigits)
+	chars = appendTimestampDigits(chars, e.offsetCode, timestampV2OffsetDigits)
+	chars = appendTimestampDigits(chars, e.utc.Year()+timestampYearBias, timestampV2YearDigits)
+	return chars
+}
+
+// appendTimestampDigits appends value as count hexadecimal digits, most significant first
+func appendTimestampDigits(chars []string, value int, count int) []string {
+	base := len(TimestampV2DigitIndex)
+	digits := make([]string, count)
+	for i := count - 1; i >= 0; i-- {
+		digits[i] = TimestampV2DigitIndex[value%base]
+		value /= base
+	}
+	return append(chars, digits...)
+}
+
+// readTimestampV2Extension decodes the version 2 fields of input
+// ok is false when there is no marker or the digits are not exactly those written by the encoder
+func readTimestampV2Extension(input string) (second, offsetCode, year int, ok bool) {
+	markerAt := strings.Index(input, TimestampV2Marker)
+	if markerAt < 0 {
+		return 0, 0, 0, false
+	}
+
+	digitValues := make(map[rune]int, len(TimestampV2DigitIndex))
+	for i, digit := range TimestampV2DigitIndex {
+		digitValues[[]rune(digit)[0]] = i
+	}
+	var digits []int
+	for _, r := range input[markerAt:] {
+		if value, isDigit := digitValues[r]; isDigit {
+			digits = append(digits, value)
+		}
+	}
+	if len(digits) != timestampV2Digits {
+		return 0, 0, 0, false
+	}
+
+	read := func(count int) int {
+		value := 0
+		for _, digit := range digits[:count] {
+			value = value*len(TimestampV2DigitIndex) + digit
+		}
+		digits = digits[count:]
+		return value
+	}
+	second = read(timestampV2SecondDigits)
+	offsetCode = read(timestampV2OffsetDigits)
+	year = read(timestampV2YearDigits) - timestampYearBias
+	if second > 59 || offsetCode > timestampOffsetUnknownLocal || year > 10000 {
+		return 0, 0, 0, false
+	}
+	return second, offsetCode, year, true
+}
+
+// formatEncodedTimestamp rebuilds the RFC 3339 timestamp written in the original offset
+func formatEncodedTimestamp(utc time.Time, offsetCode int) string {
+	var offset int
+	var suffix string
+	switch offsetCode {
+	case timestampOffsetUTC:
+		suffix = "Z"
+	case timestampOffsetUnknownLocal:
+		suffix = "-00:00"
+	default:
+		offset = offsetCode - timestampOffsetBias
+		sign := '+'
+		abs := offset
+		if offset < 0 {
+			sign = '-'
+			abs = -offset
+		}
+		suffix = fmt.Sprintf("%c%02d:%02d", sign, abs/60, abs%60)
+	}
+
+	local := utc.Add(time.Duration(offset) * time.Minute)
+	return fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02d%s",
+		local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), suffix)
+}
@@ -0,0 +1,137 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// FuzzTimestampV2RoundTrip tests that any RFC 3339 timestamp line survives a translation unchanged
+func FuzzTimestampV2RoundTrip(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("hello world", int64(0), 0)
+	f.Add("", int64(1760884245), 330)
+	f.Add("x", int64(-62135596800), -1439)
+	f.Add("a b c", int64(253402300799), 1439)
+	f.Fuzz(func(t *testing.T, text string, unix int64, offsetMinutes int) {
+		offsetMinutes %= 1440
+		// Keep the local wall clock within years 0000-9999, the range RFC 3339 can write
+		local := time.Unix(unix, 0).In(time.FixedZone("", offsetMinutes*60))
+		if local.Year() < 0 || local.Year() > 9999 {
+			return
+		}
+
+		timestamps := []string{local.Format(time.RFC3339)}
+		if offsetMinutes == 0 {
+			// The same instant written with an explicit or unknown offset must keep that spelling
+			timestamps = append(timestamps, local.Format("2006-01-02T15:04:05")+"+00:00", local.Format("2006-01-02T15:04:05")+"-00:00")
+		}
+
+		for _, timestamp := range timestamps {
+			input := RemoveTimestampSpecialCharacters(text)
+			encoded := addSpecialCharDatetimeEncodingWith(input, timestamp, time.Now, rand.New(rand.NewSource(unix)))
+			if decoded := readTimestampUsingSpecialCharEncoding(encoded); decoded != timestamp {
+				t.Errorf("timestamp round-trip failed\nTimestamp: %q\nEncoded:   %q\nDecoded:   %q", timestamp, encoded, decoded)
+			}
+			if stripped := RemoveTimestampSpecialCharacters(encoded); stripped != input {
+				t.Errorf("RemoveTimestampSpecialCharacters() = %q, want %q", stripped, input)
+			}
+		}
+	})
+}
+
+// TestReadTimestampV1 verifies texts written with the version 1 encoding still decode
+func TestReadTimestampV1(t *testing.T) {
+	// Version 1 wrote one character each for day, month, year, hour and minute
+	v1 := fmt.Sprintf("%shello %sworld%s %s%s",
+		DaySpecialCharIndex[18], MonthSpecialCharIndex[9], YearSpecialCharIndex[0], HourSpecialCharIndex[14], MinuteSpecialCharIndex[30])
+
+	if got, want := readTimestampUsingSpecialCharEncoding(v1), "2025-10-19T14:30:00Z"; got != want {
+		t.Errorf("readTimestampUsingSpecialCharEncoding(v1) = %q, want %q", got, want)
+	}
+	if got, want := RemoveTimestampSpecialCharacters(v1), "hello world "; got != want {
+		t.Errorf("RemoveTimestampSpecialCharacters(v1) = %q, want %q", got, want)
+	}
+
+	// A damaged extension falls back to the version 1 reading
+	damaged := v1 + TimestampV2Marker + TimestampV2DigitIndex[3]
+	if got, want := readTimestampUsingSpecialCharEncoding(damaged), "2025-10-19T14:30:00Z"; got != want {
+		t.Errorf("readTimestampUsingSpecialCharEncoding(damaged) = %q, want %q", got, want)
+	}
+}
+
+// FuzzExtractTimestamp tests that the timestamp hidden by a translation is extracted untampered
+func FuzzExtractTimestamp(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("", int64(0))
+	f.Add("Hello, World! 123", int64(1760884245))
+	f.Fuzz(func(t *testing.T, input string, unix int64) {
+		if _, existing := removeISO8601timestamp(input); existing != "" {
+			return
+		}
+		timestamp := time.Unix(unix, 0).In(time.FixedZone("", 5*3600+30*60))
+		if timestamp.Year() < 0 || timestamp.Year() > 9999 {
+			return
+		}
+
+		tr := New(Options{Timestamp: timestamp, Rand: SeededRand(unix)})
+		pejelagarto := tr.ToPejelagarto(input)
+		extracted, err := ExtractTimestamp(pejelagarto)
+		if err != nil {
+			t.Fatalf("ExtractTimestamp() error: %v\nPejelagarto: %q", err, pejelagarto)
+		}
+		if !extracted.Time.Equal(timestamp) || extracted.Tampered {
+			t.Errorf("ExtractTimestamp() = %v, tampered %v, want %v\nPejelagarto: %q", extracted.Time, extracted.Tampered, timestamp, pejelagarto)
+		}
+
+		runes := []rune(pejelagarto)
+		specialChars := timestampSpecialChars()
+		for _, marker := range extracted.Markers {
+			if _, isSpecial := specialChars[runes[marker.Position]]; !isSpecial {
+				t.Errorf("marker %+v does not point at a timestamp character in %q", marker, pejelagarto)
+			}
+		}
+		// The current dialect also hides its version
+		if want := 5 + 1 + timestampV2Digits + 1 + 2*rulesetFingerprintBytes; len(extracted.Markers) != want {
+			t.Errorf("found %d markers, want %d", len(extracted.Markers), want)
+		}
+	})
+}
+
+// TestExtractTimestamp verifies components, positions and tampering are reported
+func TestExtractTimestamp(t *testing.T) {
+	v1 := fmt.Sprintf("%shello %sworld%s %s",
+		DaySpecialCharIndex[18], MonthSpecialCharIndex[9], YearSpecialCharIndex[0], HourSpecialCharIndex[14])
+
+	extracted, err := ExtractTimestamp(v1)
+	if err != nil {
+		t.Fatalf("ExtractTimestamp(v1) error: %v", err)
+	}
+	if want := time.Date(2025, time.October, 19, 14, 0, 0, 0, time.UTC); !extracted.Time.Equal(want) {
+		t.Errorf("Time = %v, want %v", extracted.Time, want)
+	}
+	if got := fmt.Sprint(extracted.Components); got != "[day month year hour]" {
+		t.Errorf("Components = %s", got)
+	}
+	if got := fmt.Sprint(extracted.Markers); got != "[{day 0} {month 7} {year 13} {hour 15}]" {
+		t.Errorf("Markers = %s", got)
+	}
+	if extracted.Tampered {
+		t.Errorf("untouched text reported as tampered")
+	}
+
+	extracted, _ = ExtractTimestamp(v1 + DaySpecialCharIndex[3])
+	if !extracted.Tampered {
+		t.Errorf("second day character not reported as tampered")
+	}
+	extracted, _ = ExtractTimestamp(v1 + TimestampV2Marker + TimestampV2DigitIndex[3])
+	if !extracted.Tampered {
+		t.Errorf("damaged version 2 digits not reported as tampered")
+	}
+
+	if _, err := ExtractTimestamp("hello world"); !errors.Is(err, ErrNoTimestamp) {
+		t.Errorf("ExtractTimestamp(plain) error = %v, want ErrNoTimestamp", err)
+	}
+}
@@ -2,9 +2,9 @@ package translator
 
 import (
 	"fmt"
-	"math/big"
 	"math/rand"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -16,6 +16,8 @@ import (
 // NOTE: All values must have SAME length as keys (rune count)
 // NOTE: Output values use ONLY letters NOT in LetterMap (c,h,j,s,t,x,z) to avoid collisions
 // NOTE: Avoid repeated characters to prevent ambiguity (e.g., "zz" could be confused with "z"+"z")
+// The first values predate these NOTEs and are kept so that existing texts decode: Ruleset.ValidateStrict
+// reports their collisions ("arak" starts "araka", so "holau" is read back as "hello")
 var ConjunctionMap = map[string]string{
 	"hello": "araka",
 	"hola":  "arak",
@@ -35,6 +37,7 @@ var ConjunctionMap = map[string]string{
 // to prevent collisions between letter outputs and conjunction inputs
 // NOTE: Consonants map to consonants, vowels map to vowels (y and w are vowels)
 // NOTE: Each letter must map to another letter that maps back to it (true bijective pairs)
+// NOTE: Keys and values are lowercase, the case of the text is applied to them
 var LetterMap = map[string]string{
 	"a": "u",
 	"b": "p",
@@ -74,9 +77,10 @@ var PunctuationMap = map[string]string{
 }
 
 // Escape characters for internal and output escaping
-const (
-	InternalEscapeChar = '\\'     // Backslash - used internally, removed before output
-	OutputEscapeChar   = '\u00AD' // Soft hyphen - used in output, visible in Pejelagarto text
+// These can be overridden by a ruleset (see UseRuleset)
+var (
+	InternalEscapeChar rune = '\\'     // Backslash - used internally, removed before output
+	OutputEscapeChar   rune = '\u00AD' // Soft hyphen - used in output, visible in Pejelagarto text
 )
 
 // Special character encoding maps for datetime using Unicode range U+2300 to U+23FB (avoiding emojis)
@@ -153,41 +157,8 @@ var MinuteSpecialCharIndex = []string{
 	"\u309A", "\u309B", "\u309C", "\uA702", "\uAAB8", "\u061C", "\uA950", "\uA951", "\uA926", "\uA952",
 }
 
-// validateMaps checks that all mappings have equal rune lengths for keys and values
-func validateMaps() error {
-	maps := []struct {
-		name string
-		m    map[string]string
-	}{
-		{"ConjunctionMap", ConjunctionMap},
-		{"LetterMap", LetterMap},
-	}
-
-	for _, mapInfo := range maps {
-		for key, value := range mapInfo.m {
-			keyLen := utf8.RuneCountInString(key)
-			valueLen := utf8.RuneCountInString(value)
-			if keyLen != valueLen {
-				return fmt.Errorf("%s: key %q (len=%d) and value %q (len=%d) must have equal rune lengths",
-					mapInfo.name, key, keyLen, value, valueLen)
-			}
-		}
-	}
-	return nil
-}
-
-// createBijectiveMap creates a unified bijective map from the three replacement maps
-func createBijectiveMap() map[int32]map[string]string {
-	// Validate that all maps have equal-length key-value pairs
-	if err := validateMaps(); err != nil {
-		panic(err)
-	}
-
-	// Validate accent wheels on first call
-	if err := validateAccentWheels(); err != nil {
-		panic(err)
-	}
-
+// createBijectiveMapFrom creates a unified bijective map from the given replacement maps
+func createBijectiveMapFrom(sourceMaps ...map[string]string) map[int32]map[string]string {
 	bijectiveMap := make(map[int32]map[string]string)
 
 	// Helper function to add entries to the map
@@ -232,17 +203,20 @@ func createBijectiveMap() map[int32]map[string]string {
 	}
 
 	// Add positive entries (key -> value)
-	addEntries(ConjunctionMap, true)
-	addEntries(LetterMap, true)
+	for _, sourceMap := range sourceMaps {
+		addEntries(sourceMap, true)
+	}
 
 	// Add inverse entries (-index: value -> key)
-	addEntries(ConjunctionMap, false)
-	addEntries(LetterMap, false)
+	for _, sourceMap := range sourceMaps {
+		addEntries(sourceMap, false)
+	}
 
 	return bijectiveMap
 }
 
 // getSortedIndices returns indices sorted appropriately for the direction
+// Glossary pairs have indices above glossaryIndexBase, so they come first in their sign group
 func getSortedIndices(bijectiveMap map[int32]map[string]string, toPejelagarto bool) []int32 {
 	indices := make([]int32, 0, len(bijectiveMap))
 	for index := range bijectiveMap {
@@ -410,242 +384,13 @@ func outputUnescape(input string) string {
 	return result.String()
 }
 
-// applyReplacements applies replacements from the bijective map in the specified order
-func applyReplacements(input string, bijectiveMap map[int32]map[string]string, indices []int32) string {
-	// Use special Unicode characters as markers that won't be in normal text
-	const startMarker = "\uFFF0"
-	const endMarker = "\uFFF1"
-
-	// Escape any markers that appear in the input to preserve them
-	// Convert markers to runes for proper escaping
-	startMarkerRune := []rune(startMarker)[0]
-	endMarkerRune := []rune(endMarker)[0]
-	result := internalEscape(input, string([]rune{startMarkerRune, endMarkerRune}))
-
-	for _, index := range indices {
-		replacements := bijectiveMap[index]
-
-		// Sort keys by length descending, then alphabetically
-		keys := make([]string, 0, len(replacements))
-		for key := range replacements {
-			keys = append(keys, key)
-		}
-		sort.Slice(keys, func(i, j int) bool {
-			if len(keys[i]) != len(keys[j]) {
-				return len(keys[i]) > len(keys[j])
-			}
-			return keys[i] < keys[j]
-		})
-
-		for _, key := range keys {
-			value := replacements[key]
-
-			// For FromPejelagarto: if the key has a quote prefix (Pejelagarto multi-rune pattern),
-			// remove it from the output value so it doesn't appear in Human text
-			outputValue := value
-			if strings.HasPrefix(key, "'") {
-				// This is a Pejelagarto pattern being converted back to Human
-				// Remove any quote prefix from the output
-				outputValue = strings.TrimPrefix(value, "'")
-			}
-
-			// Find and replace all occurrences (case-insensitive)
-			newResult := strings.Builder{}
-			pos := 0
-			resultRunes := []rune(result)
-			keyRunes := []rune(key)
-
-			// Pre-calculate marker positions and escape positions for O(n) performance
-			markerMap := make(map[int]int)   // pos -> marker depth at that position
-			escapedMap := make(map[int]bool) // pos -> is this position escaped
-			depth := 0
-			startMarkerRune := []rune(startMarker)[0]
-			endMarkerRune := []rune(endMarker)[0]
-
-			// First pass: identify escaped characters
-			// We need to check for BOTH backslash escapes (internal) and soft hyphen escapes (output)
-			for i := 0; i < len(resultRunes); i++ {
-				if (resultRunes[i] == InternalEscapeChar || resultRunes[i] == OutputEscapeChar) && i+1 < len(resultRunes) {
-					escapedMap[i+1] = true
-					escapedMap[i] = true // Mark the escape character itself as escaped too
-				}
-			}
-
-			// Second pass: calculate marker depth, skipping escaped characters
-			for i := 0; i < len(resultRunes); i++ {
-				markerMap[i] = depth
-
-				// Only count as marker if not escaped
-				if !escapedMap[i] {
-					if resultRunes[i] == startMarkerRune {
-						depth++
-					} else if resultRunes[i] == endMarkerRune {
-						depth--
-					}
-				}
-			}
-
-			for pos < len(resultRunes) {
-				// Check if current character is escaped
-				if escapedMap[pos] {
-					// This character is escaped, just copy it
-					newResult.WriteRune(resultRunes[pos])
-					pos++
-					continue
-				}
-
-				// Check if we're inside markers using pre-calculated map
-				if markerMap[pos] > 0 {
-					// Skip characters inside markers
-					newResult.WriteRune(resultRunes[pos])
-					pos++
-					continue
-				}
-
-				// Check if we're inside a quoted multi-rune pattern (for non-quoted keys only)
-				// A quote at the start of a word protects the entire word from being re-matched
-				// BUT only if the quote hasn't been processed yet (i.e., not inside markers)
-				if len(keyRunes) > 0 && keyRunes[0] != '\'' {
-					inQuotedWord := false
-					// Look backwards in the current word for an unprocessed quote
-					// Optimized: limit backward scan to word boundary
-					wordStart := pos
-					for i := pos - 1; i >= 0 && i >= pos-50; i-- { // limit backward scan
-						if !unicode.IsLetter(resultRunes[i]) && resultRunes[i] != '\'' {
-							wordStart = i + 1
-							break
-						}
-						if i == 0 {
-							wordStart = 0
-						}
-					}
-
-					for i := pos - 1; i >= wordStart; i-- {
-						if resultRunes[i] == '\'' {
-							// Check if this quote is inside markers using pre-calculated map
-							if markerMap[i] == 0 {
-								inQuotedWord = true
-							}
-							break
-						}
-						if !unicode.IsLetter(resultRunes[i]) {
-							break
-						}
-					}
-					if inQuotedWord {
-						// Skip this character - it's part of a quoted pattern
-						newResult.WriteRune(resultRunes[pos])
-						pos++
-						continue
-					}
-				}
-
-				// Check if current position matches the key (case-insensitive)
-				if pos+len(keyRunes) <= len(resultRunes) {
-					matched := true
-					matchEndPos := pos + len(keyRunes)
-
-					// Check if the match would span across a quote character (but not start with one)
-					// Quotes act as boundaries - patterns can't span across them
-					if len(keyRunes) > 0 && keyRunes[0] != '\'' {
-						for i := pos; i < matchEndPos; i++ {
-							if resultRunes[i] == '\'' {
-								matched = false
-								break
-							}
-						}
-					}
-
-					// Check if any part of the potential match is inside markers
-					// Use pre-calculated marker map
-					if matched {
-						for i := pos; i < matchEndPos; i++ {
-							if markerMap[i] > 0 {
-								matched = false
-								break
-							}
-						}
-					}
-
-					// Also check for case-insensitive character match
-					if matched {
-						for i := 0; i < len(keyRunes); i++ {
-							resultChar := resultRunes[pos+i]
-							keyChar := keyRunes[i]
-
-							// Check if characters match (case-insensitive)
-							if unicode.ToLower(resultChar) != unicode.ToLower(keyChar) {
-								matched = false
-								break
-							}
-
-							// Additional check: ensure case conversion is reversible for this character
-							// Skip match if either character has non-reversible case conversion
-							if unicode.IsLetter(resultChar) {
-								// Check if upper->lower->upper is reversible
-								if unicode.ToUpper(unicode.ToLower(resultChar)) != unicode.ToUpper(resultChar) {
-									matched = false
-									break
-								}
-							}
-							if unicode.IsLetter(keyChar) {
-								// Check if upper->lower->upper is reversible
-								if unicode.ToUpper(unicode.ToLower(keyChar)) != unicode.ToUpper(keyChar) {
-									matched = false
-									break
-								}
-							}
-						}
-					}
-
-					if matched {
-						// Extract matched text with original casing
-						matchedText := string(resultRunes[pos : pos+len(keyRunes)])
-						// Apply case matching
-						casedValue := matchCase(matchedText, outputValue)
-						// Wrap in markers and add
-						newResult.WriteString(startMarker)
-						newResult.WriteString(casedValue)
-						newResult.WriteString(endMarker)
-						pos += len(keyRunes)
-						continue
-					}
-				}
-
-				// No match, just copy the character
-				newResult.WriteRune(resultRunes[pos])
-				pos++
-			}
-
-			result = newResult.String()
-		}
-	}
-
-	// Remove all working markers, but NOT escaped ones
-	// We need to manually iterate to skip escaped markers
-	var cleanResult strings.Builder
-	resultRunes := []rune(result)
-	for i := 0; i < len(resultRunes); i++ {
-		// Check if this is an escaped character (preceded by backslash)
-		isEscaped := i > 0 && resultRunes[i-1] == InternalEscapeChar
-
-		// Skip unescaped markers
-		if !isEscaped && (resultRunes[i] == startMarkerRune || resultRunes[i] == endMarkerRune) {
-			continue
-		}
-
-		cleanResult.WriteRune(resultRunes[i])
-	}
-	result = cleanResult.String()
-
-	// THEN restore escaped characters (this restores original markers that were in the input)
-	result = internalUnescape(result)
-
-	return result
-}
-
 // applyMapReplacementsToPejelagarto translates text to Pejelagarto using map replacements
 func applyMapReplacementsToPejelagarto(input string) string {
+	return withCurrentRules(input, (*compiledRules).replaceMapsToPejelagarto)
+}
+
+// replaceMapsToPejelagarto translates text to Pejelagarto using the dialect's map replacements
+func (c *compiledRules) replaceMapsToPejelagarto(input string) string {
 	// If input is not valid UTF-8, return it unchanged
 	if !utf8.ValidString(input) {
 		return input
@@ -655,23 +400,29 @@ func applyMapReplacementsToPejelagarto(input string) string {
 	// This will be visible in the Pejelagarto output
 	input = outputEscape(input, "'")
 
-	bijectiveMap := createBijectiveMap()
-	indices := getSortedIndices(bijectiveMap, true)
-	result := applyReplacements(input, bijectiveMap, indices)
+	result := c.mapsToPejelagarto.apply(input)
 
 	return result
 }
 
+// replaceMapsToPejelagartoAligned is replaceMapsToPejelagarto aligning its output with its input
+func (c *compiledRules) replaceMapsToPejelagartoAligned(input string) (string, alignment) {
+	return replaceAligned(input, escapeQuotes, c.mapsToPejelagarto, nil)
+}
+
 // applyMapReplacementsFromPejelagarto translates text from Pejelagarto using map replacements
 func applyMapReplacementsFromPejelagarto(input string) string {
+	return withCurrentRules(input, (*compiledRules).replaceMapsFromPejelagarto)
+}
+
+// replaceMapsFromPejelagarto translates text from Pejelagarto using the dialect's map replacements
+func (c *compiledRules) replaceMapsFromPejelagarto(input string) string {
 	// If input is not valid UTF-8, return it unchanged
 	if !utf8.ValidString(input) {
 		return input
 	}
 
-	bijectiveMap := createBijectiveMap()
-	indices := getSortedIndices(bijectiveMap, false) // from Pejelagarto
-	result := applyReplacements(input, bijectiveMap, indices)
+	result := c.mapsFromPejelagarto.apply(input)
 
 	// Unescape output-escaped quotes (soft hyphen prefix)
 	result = outputUnescape(result)
@@ -679,272 +430,84 @@ func applyMapReplacementsFromPejelagarto(input string) string {
 	return result
 }
 
+// replaceMapsFromPejelagartoAligned is replaceMapsFromPejelagarto aligning its output with its input
+func (c *compiledRules) replaceMapsFromPejelagartoAligned(input string) (string, alignment) {
+	return replaceAligned(input, nil, c.mapsFromPejelagarto, outputUnescape)
+}
+
+// escapeQuotes escapes quotes with the output escape character before the To Pejelagarto replacements
+func escapeQuotes(input string) string {
+	return outputEscape(input, "'")
+}
+
+// escapeQuotesAndNumbers escapes quotes and the punctuation of number literals with the output escape
+// character before the punctuation replacements, so that 3.14 keeps its point and -5 its sign
+func escapeQuotesAndNumbers(input string) string {
+	runes := []rune(input)
+	numbers := numberLiteralPunctuation(runes)
+	var result strings.Builder
+	result.Grow(len(input) + len(input)/4)
+	for i, r := range runes {
+		if r == '\'' || r == OutputEscapeChar || numbers[i] {
+			result.WriteRune(OutputEscapeChar)
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+// replaceAligned runs the replacement engine between the optional before and after steps of a
+// replace method, composing the alignment of the three
+func replaceAligned(input string, before func(string) string, engine *replacementEngine, after func(string) string) (string, alignment) {
+	if !utf8.ValidString(input) {
+		return input, identityAlignment(utf8.RuneCountInString(input))
+	}
+	cuts := identityAlignment(utf8.RuneCountInString(input))
+	if before != nil {
+		escaped := before(input)
+		cuts = diffAlignment(input, escaped)
+		input = escaped
+	}
+	result, replaced := engine.applyAligned(input)
+	cuts = composeAlignments(cuts, replaced)
+	if after != nil {
+		unescaped := after(result)
+		cuts = composeAlignments(cuts, diffAlignment(result, unescaped))
+		result = unescaped
+	}
+	return result, cuts
+}
+
 // applyNumbersLogicToPejelagarto applies number transformation for Pejelagarto encoding
 // Positive numbers: converts base-10 to base-8
 // Negative numbers: converts base-10 to base-7
-// Preserves leading zeros and handles signs separately
+// Fractions, group separators, exponents and the digits of every script are handled as whole
+// literals (see Number literals); leading zeros and signs are preserved
 // Uses arbitrary-precision arithmetic to handle any size number
 func applyNumbersLogicToPejelagarto(input string) string {
 	// If input is not valid UTF-8, return it unchanged
 	if !utf8.ValidString(input) {
 		return input
 	}
-	var result strings.Builder
-	runes := []rune(input)
-	i := 0
-
-	for i < len(runes) {
-		// Check if we're at the start of a number (including negative)
-		// Only process ASCII digits 0-9, not Unicode digits
-		if (runes[i] >= '0' && runes[i] <= '9') || (runes[i] == '-' && i+1 < len(runes) && runes[i+1] >= '0' && runes[i+1] <= '9') {
-			// Extract sign
-			isNegative := false
-			if runes[i] == '-' {
-				isNegative = true
-				i++
-			}
-
-			// Count leading zeros
-			leadingZeros := 0
-			for i < len(runes) && runes[i] == '0' {
-				leadingZeros++
-				i++
-			}
-
-			// Get the rest of the digits
-			digitStart := i
-			for i < len(runes) && runes[i] >= '0' && runes[i] <= '9' {
-				i++
-			}
-
-			numberStr := string(runes[digitStart:i])
-
-			// If only zeros, handle specially
-			if numberStr == "" {
-				// Only zeros (e.g., "00", "000", "-0")
-				if isNegative {
-					result.WriteRune('-')
-				}
-				for j := 0; j < leadingZeros; j++ {
-					result.WriteRune('0')
-				}
-			} else {
-				// Parse as big.Int
-				absValue := new(big.Int)
-				_, ok := absValue.SetString(numberStr, 10)
-				if !ok {
-					// Parse failed, preserve as-is
-					if isNegative {
-						result.WriteRune('-')
-					}
-					for j := 0; j < leadingZeros; j++ {
-						result.WriteRune('0')
-					}
-					result.WriteString(numberStr)
-					continue
-				}
-
-				// Convert to base-8 for positive, base-7 for negative
-				var convertedStr string
-				if isNegative {
-					// Negative: convert to base-7
-					convertedStr = absValue.Text(7)
-				} else {
-					// Positive: convert to base-8
-					convertedStr = absValue.Text(8)
-				}
-
-				// Write sign if negative
-				if isNegative {
-					result.WriteRune('-')
-				}
-				// Preserve leading zeros
-				for j := 0; j < leadingZeros; j++ {
-					result.WriteRune('0')
-				}
-				result.WriteString(convertedStr)
-			}
-		} else {
-			result.WriteRune(runes[i])
-			i++
-		}
-	}
-
-	return result.String()
+	return convertNumbers(input, true)
 }
 
 // ApplyNumbersLogicFromPejelagarto applies number transformation from Pejelagarto encoding
 // Positive numbers: converts base-8 to base-10
 // Negative numbers: converts base-7 to base-10
-// Preserves leading zeros and handles signs separately
+// Literals with digits outside their base (e.g. 8 and 9 in a positive number) are preserved as-is
 // Uses arbitrary-precision arithmetic to handle any size number
 func ApplyNumbersLogicFromPejelagarto(input string) string {
 	// If input is not valid UTF-8, return it unchanged
 	if !utf8.ValidString(input) {
 		return input
 	}
-	var result strings.Builder
-	runes := []rune(input)
-	i := 0
-
-	for i < len(runes) {
-		// Check if we're at the start of a number (base 7 or base 8, including negative)
-		if isBase8Digit(runes[i]) || (runes[i] == '-' && i+1 < len(runes) && isBase7Digit(runes[i+1])) {
-			// Extract sign
-			isNegative := false
-			if runes[i] == '-' {
-				isNegative = true
-				i++
-			}
-
-			// Count leading zeros
-			leadingZeros := 0
-			for i < len(runes) && runes[i] == '0' {
-				leadingZeros++
-				i++
-			}
-
-			// Get the rest of the digits
-			// For positive: base-8 (0-7), for negative: base-7 (0-6)
-			digitStart := i
-			if isNegative {
-				// Negative: expect base-7 digits (0-6)
-				for i < len(runes) && isBase7Digit(runes[i]) {
-					i++
-				}
-
-				// Check if followed by digits 7-9 (which means it's actually a base-10 number, not base-7)
-				// If so, we need to consume those digits and skip transformation
-				hasHighDigits := false
-				highDigitEnd := i
-				if i < len(runes) && runes[i] >= '7' && runes[i] <= '9' {
-					hasHighDigits = true
-					// Consume remaining base-10 digits
-					for i < len(runes) && runes[i] >= '0' && runes[i] <= '9' {
-						i++
-					}
-					highDigitEnd = i
-				}
-
-				// If we found digits 7-9, this is a base-10 number, not base-7 - preserve as-is
-				if hasHighDigits {
-					numberStr := string(runes[digitStart:highDigitEnd])
-					if isNegative {
-						result.WriteRune('-')
-					}
-					for j := 0; j < leadingZeros; j++ {
-						result.WriteRune('0')
-					}
-					result.WriteString(numberStr)
-					continue
-				}
-			} else {
-				// Positive: expect base-8 digits (0-7)
-				for i < len(runes) && isBase8Digit(runes[i]) {
-					i++
-				}
-
-				// Check if followed by digits 8-9 (which means it's actually a base-10 number, not base-8)
-				// If so, we need to consume those digits and skip transformation
-				hasHighDigits := false
-				highDigitEnd := i
-				if i < len(runes) && runes[i] >= '8' && runes[i] <= '9' {
-					hasHighDigits = true
-					// Consume remaining base-10 digits
-					for i < len(runes) && runes[i] >= '0' && runes[i] <= '9' {
-						i++
-					}
-					highDigitEnd = i
-				}
-
-				// If we found digits 8-9, this is a base-10 number, not base-8 - preserve as-is
-				if hasHighDigits {
-					numberStr := string(runes[digitStart:highDigitEnd])
-					for j := 0; j < leadingZeros; j++ {
-						result.WriteRune('0')
-					}
-					result.WriteString(numberStr)
-					continue
-				}
-			}
-
-			numberStr := string(runes[digitStart:i])
-
-			// If only zeros, handle specially
-			if numberStr == "" {
-				// Only zeros (e.g., "00", "000", "-0")
-				if isNegative {
-					result.WriteRune('-')
-				}
-				for j := 0; j < leadingZeros; j++ {
-					result.WriteRune('0')
-				}
-			} else {
-				// Parse as big.Int from appropriate base
-				var base10Str string
-				if isNegative {
-					// Negative: parse from base-7
-					base7Value := new(big.Int)
-					_, ok := base7Value.SetString(numberStr, 7)
-					if !ok {
-						// Parse failed, preserve as-is
-						if isNegative {
-							result.WriteRune('-')
-						}
-						for j := 0; j < leadingZeros; j++ {
-							result.WriteRune('0')
-						}
-						result.WriteString(numberStr)
-						continue
-					}
-					base10Str = base7Value.Text(10)
-				} else {
-					// Positive: parse from base-8
-					base8Value := new(big.Int)
-					_, ok := base8Value.SetString(numberStr, 8)
-					if !ok {
-						// Parse failed, preserve as-is
-						for j := 0; j < leadingZeros; j++ {
-							result.WriteRune('0')
-						}
-						result.WriteString(numberStr)
-						continue
-					}
-					base10Str = base8Value.Text(10)
-				}
-
-				// Write sign if negative
-				if isNegative {
-					result.WriteRune('-')
-				}
-				// Preserve leading zeros
-				for j := 0; j < leadingZeros; j++ {
-					result.WriteRune('0')
-				}
-				result.WriteString(base10Str)
-			}
-		} else {
-			result.WriteRune(runes[i])
-			i++
-		}
-	}
-
-	return result.String()
-}
-
-// isBase7Digit checks if a rune is a valid base 7 digit (0-6)
-func isBase7Digit(r rune) bool {
-	return r >= '0' && r <= '6'
-}
-
-// isBase8Digit checks if a rune is a valid base 8 digit (0-7)
-func isBase8Digit(r rune) bool {
-	return r >= '0' && r <= '7'
+	return convertNumbers(input, false)
 }
 
 // Accent wheels for vowel replacement
 // OneRuneAccentsWheel: single-rune accent forms (1 rune input → 1 rune output)
-// TwoRunesAccentsWheel: two-rune accent forms (2 runes input → 2 runes output)
+// TwoRunesAccentsWheel: two-rune accent forms (base vowel + combining mark), appended to the one rune wheel
 // Each vowel has its own independent wheel - position 3 for 'a' can be different from position 3 for 'e'
 // Only includes accents with reversible case conversion (ToUpper then ToLower returns original)
 
@@ -970,84 +533,68 @@ var TwoRunesAccentsWheel = map[rune][]string{
 	'y': {"y\u0328"},                       // y+ogonek (2 runes)
 }
 
-// validateAccentWheels checks that all accent forms have the expected rune count and case reversibility
-func validateAccentWheels() error {
-	// Validate OneRuneAccentsWheel - all values should be single runes with reversible case
-	for baseVowel, accents := range OneRuneAccentsWheel {
-		for idx, accentedForm := range accents {
-			runeCount := utf8.RuneCountInString(accentedForm)
-			if runeCount != 1 {
-				return fmt.Errorf("OneRuneAccentsWheel['%c'][%d] = %q has %d runes, expected 1",
-					baseVowel, idx, accentedForm, runeCount)
-			}
+// accentWheels holds the accent wheels of a dialect
+// The stage moves each vowel along the wheel of its base vowel: the one rune forms followed by
+// the two rune forms, so that 'a' goes a, à, ..., ă, a+ogonek, a+caron and back to a
+type accentWheels struct {
+	oneRune  map[rune][]string
+	twoRunes map[rune][]string
+	wheels   map[rune][]string // one rune forms then two rune forms of each base vowel
+	bases    map[string]rune   // base vowel of every form
+}
 
-			// Check case reversibility
-			r := []rune(accentedForm)[0]
-			upperForm := unicode.ToUpper(r)
-			if unicode.ToLower(upperForm) != r {
-				return fmt.Errorf("OneRuneAccentsWheel['%c'][%d] = %q has non-reversible case conversion",
-					baseVowel, idx, accentedForm)
-			}
+// newAccentWheels combines the one rune and two rune wheels of a dialect
+func newAccentWheels(oneRune, twoRunes map[rune][]string) accentWheels {
+	w := accentWheels{
+		oneRune:  oneRune,
+		twoRunes: twoRunes,
+		wheels:   make(map[rune][]string, len(oneRune)),
+		bases:    make(map[string]rune),
+	}
+	for base, forms := range oneRune {
+		wheel := make([]string, 0, len(forms)+len(twoRunes[base]))
+		wheel = append(wheel, forms...)
+		wheel = append(wheel, twoRunes[base]...)
+		w.wheels[base] = wheel
+		for _, form := range wheel {
+			w.bases[form] = base
 		}
 	}
+	return w
+}
 
-	// Validate TwoRunesAccentsWheel - all values should be exactly 2 runes (combining character sequences)
-	// Format: base character + combining diacritic
-	for baseVowel, accents := range TwoRunesAccentsWheel {
-		for idx, accentedForm := range accents {
-			runeCount := utf8.RuneCountInString(accentedForm)
-			if runeCount != 2 {
-				return fmt.Errorf("TwoRunesAccentsWheel['%c'][%d] = %q has %d runes, expected 2",
-					baseVowel, idx, accentedForm, runeCount)
-			}
-
-			// Check case reversibility for the base character (first rune)
-			runes := []rune(accentedForm)
-			baseChar := runes[0]
-			upperForm := unicode.ToUpper(baseChar)
-			if unicode.ToLower(upperForm) != baseChar {
-				return fmt.Errorf("TwoRunesAccentsWheel['%c'][%d] = %q has non-reversible case conversion for base char",
-					baseVowel, idx, accentedForm)
-			}
+// accentClusters returns the rune index where each cluster of runes starts
+// A cluster is a rune and the combining marks after it, so a two rune form counts as one vowel and
+// the count the prime factorization sees does not change when a vowel moves between one and two runes
+func accentClusters(runes []rune) []int {
+	starts := make([]int, 0, len(runes))
+	for i, r := range runes {
+		if i == 0 || !unicode.Is(unicode.M, r) {
+			starts = append(starts, i)
 		}
 	}
+	return starts
+}
 
-	return nil
-} // isVowel checks if a rune is a vowel (including y and accented forms)
-func isVowel(r rune) bool {
-	lower := unicode.ToLower(r)
-
-	// Verify case conversion is reversible if character is uppercase
+// isVowel checks if a cluster is a form of an accent wheel, in either case
+func (w accentWheels) isVowel(cluster []rune) bool {
+	// Verify case conversion is reversible if the character is uppercase
 	// This prevents issues with characters like İ (Turkish I with dot, U+0130)
 	// which lowercase to 'i' but ToUpper('i') != 'İ'
-	if unicode.IsUpper(r) {
-		if unicode.ToUpper(lower) != r {
-			return false // Not reversible, don't treat as vowel
-		}
-	}
-
-	lowerStr := string(lower)
-
-	// Check OneRuneAccentsWheel
-	for _, accents := range OneRuneAccentsWheel {
-		for _, accentedForm := range accents {
-			if accentedForm == lowerStr {
-				return true
-			}
-		}
+	first := cluster[0]
+	if unicode.IsUpper(first) && unicode.ToUpper(unicode.ToLower(first)) != first {
+		return false
 	}
+	_, ok := w.bases[lowerCluster(cluster)]
+	return ok
+}
 
-	// Check TwoRunesAccentsWheel
-	for _, accents := range TwoRunesAccentsWheel {
-		for _, accentedForm := range accents {
-			if accentedForm == lowerStr {
-				return true
-			}
-		}
-	}
+// lowerCluster returns a cluster with its first rune lowercased
+func lowerCluster(cluster []rune) string {
+	return string(unicode.ToLower(cluster[0])) + string(cluster[1:])
+}
 
-	return false
-} // primeFactorize returns prime factors with their powers
+// primeFactorize returns prime factors with their powers
 // Example: 245 -> map[5:1, 7:2] means 5^1 * 7^2
 func primeFactorize(n int) map[int]int {
 	factors := make(map[int]int)
@@ -1077,233 +624,143 @@ func primeFactorize(n int) map[int]int {
 	return factors
 }
 
-// findAccentIndex finds the current accent index for a vowel in OneRuneAccentsWheel
-func findAccentIndex(baseVowel rune, vowelStr string) int {
-	accents, ok := OneRuneAccentsWheel[baseVowel]
-	if !ok {
-		return 0
-	}
-	for idx, accentedForm := range accents {
-		if accentedForm == vowelStr {
-			return idx
-		}
-	}
-	return 0 // Default to no accent
+// applyAccentReplacementLogicToPejelagarto applies accent changes based on prime factorization
+func applyAccentReplacementLogicToPejelagarto(input string) string {
+	return withCurrentRules(input, func(c *compiledRules, input string) string { return c.wheels.applyToPejelagarto(input) })
 }
 
-// getBaseVowel gets the base vowel character (lowercase, no accent)
-func getBaseVowel(vowelStr string) rune {
-	// Try to find in OneRuneAccentsWheel
-	for baseVowel, accents := range OneRuneAccentsWheel {
-		for _, accentedForm := range accents {
-			if accentedForm == vowelStr {
-				return baseVowel
-			}
-		}
-	}
-	// Try to find in TwoRunesAccentsWheel
-	for baseVowel, accents := range TwoRunesAccentsWheel {
-		for _, accentedForm := range accents {
-			if accentedForm == vowelStr {
-				return baseVowel
-			}
-		}
-	}
-	// If not found, return first rune as fallback
-	runes := []rune(vowelStr)
-	if len(runes) > 0 {
-		return unicode.ToLower(runes[0])
-	}
-	return 'a'
+// applyToPejelagarto applies accent changes based on prime factorization
+func (w accentWheels) applyToPejelagarto(input string) string {
+	return w.apply(input, 1)
 }
 
-// applyAccentReplacementLogicToPejelagarto applies accent changes based on prime factorization
-func applyAccentReplacementLogicToPejelagarto(input string) string {
+// applyAccentReplacementLogicFromPejelagarto reverses accent changes based on prime factorization
+func applyAccentReplacementLogicFromPejelagarto(input string) string {
+	return withCurrentRules(input, func(c *compiledRules, input string) string { return c.wheels.applyFromPejelagarto(input) })
+}
+
+// applyFromPejelagarto reverses accent changes based on prime factorization
+func (w accentWheels) applyFromPejelagarto(input string) string {
+	return w.apply(input, -1)
+}
+
+// apply moves the vowels selected by the prime factors of the cluster count along their wheels,
+// forward (direction 1) or backward (direction -1)
+// The nth vowel moves by the power of the nth prime; every form is a single cluster, so the
+// cluster count and the vowel positions are the same in both directions
+func (w accentWheels) apply(input string, direction int) string {
 	if !utf8.ValidString(input) {
 		return input
 	}
 
 	runes := []rune(input)
-	totalCount := len(runes)
-
-	if totalCount == 0 {
-		return input
+	starts := accentClusters(runes)
+	cluster := func(i int) []rune {
+		end := len(runes)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		return runes[starts[i]:end]
 	}
 
 	// Get prime factors
-	factors := primeFactorize(totalCount)
+	factors := primeFactorize(len(starts))
 	if len(factors) == 0 {
-		return input // No factors (totalCount is 1 or 0)
+		return input // No factors (the count is 1 or 0)
 	}
 
-	// Find all vowels and their positions
-	vowelPositions := []int{}
-	for i, r := range runes {
-		if isVowel(r) {
-			vowelPositions = append(vowelPositions, i)
+	// Find all vowels, as cluster indices
+	var vowels []int
+	for i := range starts {
+		if w.isVowel(cluster(i)) {
+			vowels = append(vowels, i)
 		}
 	}
 
-	if len(vowelPositions) == 0 {
-		return input // No vowels to modify
-	}
-
-	// Apply accent changes for each prime factor
-	// Work directly with runes and ensure single-rune replacements only
-	result := make([]rune, len(runes))
-	copy(result, runes)
-
+	// Each prime selects a different vowel, so each is replaced at most once
+	replacements := make(map[int]string)
 	for prime, power := range factors {
 		// Find the nth vowel (1-indexed to match prime)
-		vowelIndex := prime - 1 // Convert to 0-indexed
-
-		if vowelIndex >= 0 && vowelIndex < len(vowelPositions) {
-			pos := vowelPositions[vowelIndex]
-			vowelRune := result[pos]
-			isUpper := unicode.IsUpper(vowelRune)
-
-			// Get current accent index and base vowel
-			vowelStr := string(unicode.ToLower(vowelRune))
-			baseVowel := getBaseVowel(vowelStr)
-			currentAccentIndex := findAccentIndex(baseVowel, vowelStr)
-
-			// Get the wheel for this vowel
-			wheel, ok := OneRuneAccentsWheel[baseVowel]
-			if !ok || len(wheel) == 0 {
-				continue // Skip if no wheel for this vowel
-			}
-
-			// Move forward by power positions
-			newAccentIndex := (currentAccentIndex + power) % len(wheel)
-
-			// Get new accented form (always single rune from our wheel)
-			newAccentedForm := wheel[newAccentIndex]
-			newAccentRunes := []rune(newAccentedForm)
-
-			// Only apply if:
-			// 1. We got a single rune
-			// 2. The new form is different from base vowel (otherwise accent info is lost)
-			if len(newAccentRunes) == 1 {
-				// Check if this accent actually changes the vowel
-				baseVowelStr := string(baseVowel)
-				if newAccentedForm != baseVowelStr || newAccentIndex == 0 {
-					if isUpper {
-						// Apply uppercase - but only if case conversion is reversible
-						upperForm := unicode.ToUpper(newAccentRunes[0])
-						if unicode.ToLower(upperForm) == newAccentRunes[0] {
-							result[pos] = upperForm
-						} else {
-							// Case conversion not reversible, keep lowercase
-							result[pos] = newAccentRunes[0]
-						}
-					} else {
-						result[pos] = newAccentRunes[0]
-					}
-				}
-			}
+		vowelIndex := prime - 1
+		if vowelIndex >= len(vowels) {
+			continue
+		}
+		vowel := cluster(vowels[vowelIndex])
+		lower := lowerCluster(vowel)
+		wheel := w.wheels[w.bases[lower]]
+
+		// Move by power positions (with wrapping)
+		newIndex := (slices.Index(wheel, lower) + direction*power) % len(wheel)
+		if newIndex < 0 {
+			newIndex += len(wheel)
 		}
+		newRunes := []rune(wheel[newIndex])
+		if unicode.IsUpper(vowel[0]) {
+			// Wheels only hold forms whose case conversion is reversible
+			newRunes[0] = unicode.ToUpper(newRunes[0])
+		}
+		replacements[vowels[vowelIndex]] = string(newRunes)
 	}
 
-	return string(result)
+	var result strings.Builder
+	result.Grow(len(input) + len(replacements))
+	for i := range starts {
+		if replacement, ok := replacements[i]; ok {
+			result.WriteString(replacement)
+		} else {
+			result.WriteString(string(cluster(i)))
+		}
+	}
+	return result.String()
 }
 
-// applyAccentReplacementLogicFromPejelagarto reverses accent changes based on prime factorization
-func applyAccentReplacementLogicFromPejelagarto(input string) string {
+// legacyApplyFromPejelagarto reverses the accent changes of texts written before the two rune
+// forms joined the wheels (see Translator.legacyText): the prime factors of the rune count select
+// single rune vowels, which move backward along the one rune wheels only
+func (w accentWheels) legacyApplyFromPejelagarto(input string) string {
 	if !utf8.ValidString(input) {
 		return input
 	}
 
 	runes := []rune(input)
-	totalCount := len(runes)
-
-	if totalCount == 0 {
-		return input
-	}
-
-	// Get prime factors
-	factors := primeFactorize(totalCount)
+	factors := primeFactorize(len(runes))
 	if len(factors) == 0 {
 		return input
 	}
 
-	// Find all vowels and their positions
-	vowelPositions := []int{}
+	// Find all vowels, as rune indices
+	var vowels []int
 	for i, r := range runes {
-		if isVowel(r) {
-			vowelPositions = append(vowelPositions, i)
+		lower := unicode.ToLower(r)
+		if unicode.IsUpper(r) && unicode.ToUpper(lower) != r {
+			continue
+		}
+		if base, ok := w.bases[string(lower)]; ok && slices.Contains(w.oneRune[base], string(lower)) {
+			vowels = append(vowels, i)
 		}
 	}
 
-	if len(vowelPositions) == 0 {
-		return input
-	}
-
-	// Apply accent changes for each prime factor (backwards)
-	result := make([]rune, len(runes))
-	copy(result, runes)
-
+	result := slices.Clone(runes)
 	for prime, power := range factors {
-		// Find the nth vowel (1-indexed to match prime)
 		vowelIndex := prime - 1
-
-		if vowelIndex >= 0 && vowelIndex < len(vowelPositions) {
-			pos := vowelPositions[vowelIndex]
-			vowelRune := result[pos]
-			isUpper := unicode.IsUpper(vowelRune)
-
-			// Get current accent index and base vowel
-			vowelStr := string(unicode.ToLower(vowelRune))
-			baseVowel := getBaseVowel(vowelStr)
-			currentAccentIndex := findAccentIndex(baseVowel, vowelStr)
-
-			// Get the wheel for this vowel
-			wheel, ok := OneRuneAccentsWheel[baseVowel]
-			if !ok || len(wheel) == 0 {
-				continue // Skip if no wheel for this vowel
-			}
-
-			// Verify the vowel is in our wheel (skip unknown accents)
-			if currentAccentIndex >= len(wheel) {
-				continue // Skip if accent index out of range
-			}
-			expectedForm := wheel[currentAccentIndex]
-			if expectedForm != vowelStr {
-				// Accent not in our wheel, skip transformation
-				continue
-			}
-
-			// Move backward by power positions (with wrapping)
-			newAccentIndex := (currentAccentIndex - power) % len(wheel)
-			if newAccentIndex < 0 {
-				newAccentIndex += len(wheel)
-			}
-
-			// Get new accented form (always single rune from our wheel)
-			newAccentedForm := wheel[newAccentIndex]
-			newAccentRunes := []rune(newAccentedForm) // Only apply if:
-			// 1. We got a single rune
-			// 2. The new form is different from base vowel (otherwise accent info is lost)
-			if len(newAccentRunes) == 1 {
-				// Check if this accent actually changes the vowel
-				baseVowelStr := string(baseVowel)
-				if newAccentedForm != baseVowelStr || newAccentIndex == 0 {
-					if isUpper {
-						// Apply uppercase - but only if case conversion is reversible
-						upperForm := unicode.ToUpper(newAccentRunes[0])
-						if unicode.ToLower(upperForm) == newAccentRunes[0] {
-							result[pos] = upperForm
-						} else {
-							// Case conversion not reversible, keep lowercase
-							result[pos] = newAccentRunes[0]
-						}
-					} else {
-						result[pos] = newAccentRunes[0]
-					}
-				}
-			}
+		if vowelIndex >= len(vowels) {
+			continue
+		}
+		pos := vowels[vowelIndex]
+		lower := string(unicode.ToLower(result[pos]))
+		wheel := w.oneRune[w.bases[lower]]
+
+		// Move backward by power positions (with wrapping)
+		newIndex := (slices.Index(wheel, lower) - power) % len(wheel)
+		if newIndex < 0 {
+			newIndex += len(wheel)
 		}
+		newRune := []rune(wheel[newIndex])[0]
+		if unicode.IsUpper(result[pos]) {
+			newRune = unicode.ToUpper(newRune)
+		}
+		result[pos] = newRune
 	}
-
 	return string(result)
 }
 
@@ -1414,54 +871,7 @@ func applyCaseReplacementLogic(input string) string {
 
 // createPunctuationBijectiveMap creates a unified bijective map for punctuation replacements
 func createPunctuationBijectiveMap() map[int32]map[string]string {
-	bijectiveMap := make(map[int32]map[string]string)
-
-	// Helper function to add entries to the map
-	addEntries := func(sourceMap map[string]string, positive bool) {
-		for key, value := range sourceMap {
-			var index int32
-			var from, to string
-
-			if positive {
-				// Positive index: key length, key -> value
-				keyLen := utf8.RuneCountInString(key)
-				index = int32(keyLen)
-				from = key
-				to = value
-				// Prefix multi-rune values with single quote
-				if utf8.RuneCountInString(value) > 1 {
-					to = "'" + to
-				}
-			} else {
-				// Negative index: value -> key (inverse)
-				valueLen := utf8.RuneCountInString(value)
-				if valueLen > 1 {
-					// Multi-rune: the actual Pejelagarto text has a quote prefix
-					index = -int32(valueLen + 1) // +1 for the quote character
-					from = "'" + value
-					to = key
-				} else {
-					// Single-rune: no quote prefix in Pejelagarto text
-					index = -int32(valueLen)
-					from = value
-					to = key
-				}
-			}
-
-			// Add to map
-			if bijectiveMap[index] == nil {
-				bijectiveMap[index] = make(map[string]string)
-			}
-			bijectiveMap[index][from] = to
-		}
-	}
-
-	// Add forward mappings (positive indices)
-	addEntries(PunctuationMap, true)
-	// Add reverse mappings (negative indices)
-	addEntries(PunctuationMap, false)
-
-	return bijectiveMap
+	return createBijectiveMapFrom(PunctuationMap)
 }
 
 // getSortedPunctuationIndices returns indices sorted for the direction
@@ -1504,29 +914,40 @@ func getSortedPunctuationIndices(bijectiveMap map[int32]map[string]string, toPej
 
 // applyPunctuationReplacementsToPejelagarto applies punctuation replacements
 func applyPunctuationReplacementsToPejelagarto(input string) string {
+	return withCurrentRules(input, (*compiledRules).replacePunctuationToPejelagarto)
+}
+
+// replacePunctuationToPejelagarto applies the dialect's punctuation replacements
+func (c *compiledRules) replacePunctuationToPejelagarto(input string) string {
 	if !utf8.ValidString(input) {
 		return input
 	}
 
-	// Escape quotes using output escaping (soft hyphen prefix)
-	input = outputEscape(input, "'")
+	// Escape quotes and the punctuation of numbers using output escaping (soft hyphen prefix)
+	input = escapeQuotesAndNumbers(input)
 
-	bijectiveMap := createPunctuationBijectiveMap()
-	indices := getSortedPunctuationIndices(bijectiveMap, true)
-	result := applyReplacements(input, bijectiveMap, indices)
+	result := c.punctuationToPejelagarto.apply(input)
 
 	return result
 }
 
+// replacePunctuationToPejelagartoAligned is replacePunctuationToPejelagarto aligning its output with its input
+func (c *compiledRules) replacePunctuationToPejelagartoAligned(input string) (string, alignment) {
+	return replaceAligned(input, escapeQuotesAndNumbers, c.punctuationToPejelagarto, nil)
+}
+
 // applyPunctuationReplacementsFromPejelagarto reverses punctuation replacements
 func applyPunctuationReplacementsFromPejelagarto(input string) string {
+	return withCurrentRules(input, (*compiledRules).replacePunctuationFromPejelagarto)
+}
+
+// replacePunctuationFromPejelagarto reverses the dialect's punctuation replacements
+func (c *compiledRules) replacePunctuationFromPejelagarto(input string) string {
 	if !utf8.ValidString(input) {
 		return input
 	}
 
-	bijectiveMap := createPunctuationBijectiveMap()
-	indices := getSortedPunctuationIndices(bijectiveMap, false)
-	result := applyReplacements(input, bijectiveMap, indices)
+	result := c.punctuationFromPejelagarto.apply(input)
 
 	// Unescape output-escaped quotes (soft hyphen prefix)
 	result = outputUnescape(result)
@@ -1534,6 +955,11 @@ func applyPunctuationReplacementsFromPejelagarto(input string) string {
 	return result
 }
 
+// replacePunctuationFromPejelagartoAligned is replacePunctuationFromPejelagarto aligning its output with its input
+func (c *compiledRules) replacePunctuationFromPejelagartoAligned(input string) (string, alignment) {
+	return replaceAligned(input, nil, c.punctuationFromPejelagarto, outputUnescape)
+}
+
 // removeISO8601timestamp removes ISO 8601 timestamp from the last line if present
 func removeISO8601timestamp(input string) (string, string) {
 	// ISO 8601 regex pattern for timestamps like 2025-10-19T14:30:00Z or 2025-10-19T14:30:00+00:00
@@ -1545,8 +971,9 @@ func removeISO8601timestamp(input string) (string, string) {
 	}
 
 	// Check if last line matches ISO 8601 timestamp
+	// Lines that look like one but are not a real time (e.g. month 00) stay part of the text
 	lastLine := lines[len(lines)-1]
-	if iso8601Pattern.MatchString(lastLine) {
+	if _, err := time.Parse(time.RFC3339, lastLine); err == nil && iso8601Pattern.MatchString(lastLine) {
 		// Remove the last line and return the timestamp
 		if len(lines) == 1 {
 			return "", lastLine
@@ -1574,20 +1001,10 @@ func addISO8601timestamp(input string, timestamp string) string {
 func RemoveTimestampSpecialCharacters(input string) string {
 	// Build a map of all special characters used for timestamp encoding
 	specialCharsMap := make(map[string]bool)
-	for _, char := range DaySpecialCharIndex {
-		specialCharsMap[char] = true
-	}
-	for _, char := range MonthSpecialCharIndex {
-		specialCharsMap[char] = true
-	}
-	for _, char := range YearSpecialCharIndex {
-		specialCharsMap[char] = true
-	}
-	for _, char := range HourSpecialCharIndex {
-		specialCharsMap[char] = true
-	}
-	for _, char := range MinuteSpecialCharIndex {
-		specialCharsMap[char] = true
+	for _, chars := range timestampSpecialCharTables() {
+		for _, char := range chars {
+			specialCharsMap[char] = true
+		}
 	}
 
 	var result strings.Builder
@@ -1649,69 +1066,50 @@ func readTimestampUsingSpecialCharEncoding(input string) string {
 		return "" // Cannot determine datetime
 	}
 
+	// Version 2 texts also carry the seconds, the original offset and the full year
+	if second, offsetCode, fullYear, ok := readTimestampV2Extension(input); ok {
+		utc := time.Date(fullYear, time.Month(month), day, hour, minute, second, 0, time.UTC)
+		return formatEncodedTimestamp(utc, offsetCode)
+	}
+
 	// Create timestamp in ISO 8601 format
 	return fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:00Z", year, month, day, hour, minute)
 }
 
 // addSpecialCharDatetimeEncoding inserts datetime special characters at random positions
 func addSpecialCharDatetimeEncoding(input string, timestamp string) string {
-	// Use provided timestamp or current UTC datetime
-	var now time.Time
-	if timestamp == "" {
-		now = time.Now().UTC()
-	} else {
-		// Parse the ISO 8601 timestamp
-		parsedTime, err := time.Parse(time.RFC3339, timestamp)
-		if err != nil {
-			// If parsing fails, use current time
-			now = time.Now().UTC()
-		} else {
-			now = parsedTime.UTC()
-		}
-	}
-
-	// Get special character indices
-	day := now.Day() - 1          // Convert to 0-indexed
-	month := int(now.Month()) - 1 // Convert to 0-indexed
-	year := now.Year() - 2025     // Years start from 2025
-	hour := now.Hour()
-	minute := now.Minute()
+	return addSpecialCharDatetimeEncodingWith(input, timestamp, time.Now, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
 
-	// Validate indices
-	if day < 0 || day >= len(DaySpecialCharIndex) {
-		day = 0
-	}
-	if month < 0 || month >= len(MonthSpecialCharIndex) {
-		month = 0
-	}
-	if year < 0 || year >= len(YearSpecialCharIndex) {
-		year = 0
-	}
-	if hour < 0 || hour >= len(HourSpecialCharIndex) {
-		hour = 0
-	}
-	if minute < 0 || minute >= len(MinuteSpecialCharIndex) {
-		minute = 0
-	}
+// addSpecialCharDatetimeEncodingWith inserts datetime special characters using the given clock and random source
+// clock is only used when timestamp is empty or cannot be parsed
+func addSpecialCharDatetimeEncodingWith(input string, timestamp string, clock func() time.Time, rng *rand.Rand) string {
+	return insertSpecialChars(input, timestampToEncode(timestamp, clock).specialChars(), rng)
+}
 
-	// Get the special characters
-	specialChars := []string{
-		DaySpecialCharIndex[day],
-		MonthSpecialCharIndex[month],
-		YearSpecialCharIndex[year],
-		HourSpecialCharIndex[hour],
-		MinuteSpecialCharIndex[minute],
+// timestampToEncode parses timestamp, falling back to the clock when it is empty or cannot be parsed
+func timestampToEncode(timestamp string, clock func() time.Time) encodedTimestamp {
+	// Use provided timestamp or current UTC datetime
+	encoded, err := parseEncodedTimestamp(timestamp)
+	if timestamp == "" || err != nil {
+		// If there is no timestamp or parsing fails, use current time
+		encoded = encodedTimestamp{utc: clock().UTC(), offsetCode: timestampOffsetUTC}
 	}
+	return encoded
+}
 
+// insertSpecialChars inserts special characters at random positions, keeping them in order
+// No position follows OutputEscapeChar, so that lossless texts never show them escaped
+func insertSpecialChars(input string, specialChars []string, rng *rand.Rand) string {
 	// Find all positions next to spaces or line breaks
 	runes := []rune(input)
 	var positions []int
 
 	for i := 0; i < len(runes); i++ {
-		if i == 0 || runes[i] == ' ' || runes[i] == '\n' {
+		if i == 0 || (runes[i] == ' ' || runes[i] == '\n') && !escapedAt(runes, i) {
 			positions = append(positions, i)
 		}
-		if i == len(runes)-1 {
+		if i == len(runes)-1 && !escapedAt(runes, i+1) {
 			positions = append(positions, i+1)
 		}
 	}
@@ -1725,8 +1123,7 @@ func addSpecialCharDatetimeEncoding(input string, timestamp string) string {
 	}
 
 	// Shuffle positions
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(positions), func(i, j int) {
+	rng.Shuffle(len(positions), func(i, j int) {
 		positions[i], positions[j] = positions[j], positions[i]
 	})
 
@@ -1753,10 +1150,19 @@ func addSpecialCharDatetimeEncoding(input string, timestamp string) string {
 		resultRunes = append(resultRunes[:pos], append(specialCharRunes, resultRunes[pos:]...)...)
 	}
 
-	// If we couldn't insert all special characters, append the rest at the end
+	// If we couldn't insert all special characters, append the rest at the end, or after the last
+	// one inserted when the text ends with an escape character
+	at := len(resultRunes)
+	if escapedAt(resultRunes, at) {
+		at = selectedPositions[numToInsert-1]
+		for _, specialChar := range specialChars[:numToInsert] {
+			at += len([]rune(specialChar))
+		}
+	}
 	for i := numToInsert; i < len(specialChars); i++ {
 		specialCharRunes := []rune(specialChars[i])
-		resultRunes = append(resultRunes, specialCharRunes...)
+		resultRunes = append(resultRunes[:at], append(specialCharRunes, resultRunes[at:]...)...)
+		at += len(specialCharRunes)
 	}
 
 	return string(resultRunes)
@@ -1820,31 +1226,14 @@ func unsanitizeInvalidUTF8(input string) string {
 }
 
 // TranslateToPejelagarto translates Human text to Pejelagarto
+// The hidden timestamp uses the current time and random placement; use New for reproducible output
 func TranslateToPejelagarto(input string) string {
-	input = sanitizeInvalidUTF8(input)
-	input = RemoveTimestampSpecialCharacters(input)
-	input, timestamp := removeISO8601timestamp(input)
-	input = applyNumbersLogicToPejelagarto(input)
-	input = applyPunctuationReplacementsToPejelagarto(input)
-	input = applyMapReplacementsToPejelagarto(input)
-	input = applyAccentReplacementLogicToPejelagarto(input)
-	input = applyCaseReplacementLogic(input)
-	input = addSpecialCharDatetimeEncoding(input, timestamp)
-	return input
+	return defaultTranslator.ToPejelagarto(input)
 }
 
 // TranslateFromPejelagarto translates Pejelagarto text back to Human
 func TranslateFromPejelagarto(input string) string {
-	timestamp := readTimestampUsingSpecialCharEncoding(input)
-	input = RemoveTimestampSpecialCharacters(input)
-	input = applyCaseReplacementLogic(input)
-	input = applyAccentReplacementLogicFromPejelagarto(input)
-	input = applyMapReplacementsFromPejelagarto(input)
-	input = applyPunctuationReplacementsFromPejelagarto(input)
-	input = ApplyNumbersLogicFromPejelagarto(input)
-	input = addISO8601timestamp(input, timestamp)
-	input = unsanitizeInvalidUTF8(input)
-	return input
+	return defaultTranslator.FromPejelagarto(input)
 }
 
 // HTML UI template
@@ -1,6 +1,7 @@
 package translator
 
 import (
+	"strings"
 	"testing"
 	"unicode"
 	"unicode/utf8"
@@ -35,6 +36,8 @@ func FuzzApplyNumbersLogic(f *testing.F) {
 	// Seed corpus with basic cases
 	f.Add("")
 	f.Add("123")
+	f.Add("$1,299.99 -3.14 6.02e+23 1e-9")
+	f.Add("١٢٣٫٤٥ ４２ 1.2.3 0,000.0")
 	f.Fuzz(func(t *testing.T, input string) {
 		// Test: ToPejelagarto -> FromPejelagarto
 		pejelagarto := applyNumbersLogicToPejelagarto(input)
@@ -51,6 +54,8 @@ func FuzzApplyAccentReplacementLogic(f *testing.F) {
 	// Seed corpus with basic cases
 	f.Add("")
 	f.Add("café")
+	f.Add("ea\u030C" + strings.Repeat("x", 1022))
+	f.Add("o\u031B y\u0328 \u0328ÿ\u0328 a\u0328\u0301")
 	f.Fuzz(func(t *testing.T, input string) {
 		// Test: ToPejelagarto -> FromPejelagarto
 		accented := applyAccentReplacementLogicToPejelagarto(input)
@@ -62,6 +67,109 @@ func FuzzApplyAccentReplacementLogic(f *testing.F) {
 	})
 }
 
+// FuzzApplyConsonantReplacementLogic uses fuzzing to test consonant wheel reversibility
+func FuzzApplyConsonantReplacementLogic(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("")
+	f.Add("Crunchy snacks, Çetin and Ňa")
+	f.Add("c\u0327 ç\u0301 ĈĉÇ " + strings.Repeat("nx", 40))
+	f.Fuzz(func(t *testing.T, input string) {
+		// Test: ToPejelagarto -> FromPejelagarto
+		moved := applyConsonantReplacementLogicToPejelagarto(input)
+		reversed := applyConsonantReplacementLogicFromPejelagarto(moved)
+
+		if reversed != input {
+			t.Errorf("ToPejelagarto->FromPejelagarto failed\nInput:    %q\nMoved:    %q\nReversed: %q", input, moved, reversed)
+		}
+
+		// The vowel and consonant stages count the same clusters, in either order
+		both := applyConsonantReplacementLogicToPejelagarto(applyAccentReplacementLogicToPejelagarto(input))
+		if reversed := applyAccentReplacementLogicFromPejelagarto(applyConsonantReplacementLogicFromPejelagarto(both)); reversed != input {
+			t.Errorf("accent and consonant stages failed\nInput:    %q\nMoved:    %q\nReversed: %q", input, both, reversed)
+		}
+	})
+}
+
+// TestConsonantWheels verifies the digits of the cluster count in base 3 move the consonants at triangular indices
+func TestConsonantWheels(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// 5 clusters = 12 in base 3: the 1st consonant moves 2 steps, the 3rd 1 step
+		{"ncncn", "ňcñcn"},
+		{"NCNCN", "ŇCÑCN"},
+		// 9 clusters = 100 in base 3: only the 6th consonant moves
+		{"nnnnnnnnn", "nnnnnñnnn"},
+		// 4 clusters = 11 in base 3; a consonant followed by combining marks is not a form and is not counted
+		{"n\u0301cnc", "n\u0301çnç"},
+	}
+	for _, tt := range tests {
+		got := applyConsonantReplacementLogicToPejelagarto(tt.input)
+		if got != tt.want {
+			t.Errorf("applyConsonantReplacementLogicToPejelagarto(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	// Texts translated before the consonant stage, their timestamp hidden but no ruleset version
+	baselines := []struct {
+		pejelagarto string
+		want        string
+	}{
+		{"\u230FCUD\u00E9\uA4FC k\u00E9San\u00E9\uFE71 Mu\u00EFqw\u02B9\u2DFB", "café résumé naïve"},
+		{"\u230F\u00E7U\uA4FC q\u00D9\u203D\uFE71 \u00E0\u00C9\u00CE 2\u00AD'..5w12\u02B9 1\u060C000\u2DFB", "Ça va? ÀÉÎ 2.5e10 1,000"},
+	}
+	for _, tt := range baselines {
+		if got, _ := removeISO8601timestamp(TranslateFromPejelagarto(tt.pejelagarto)); got != tt.want {
+			t.Errorf("TranslateFromPejelagarto(baseline %q) = %q, want %q", tt.want, got, tt.want)
+		}
+	}
+}
+
+// TestTwoRunesAccents verifies vowels move onto the two rune forms at the end of their wheels
+func TestTwoRunesAccents(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// 1024 = 2^10 clusters: the second vowel moves 10 steps, past the 9 one rune forms of 'a'
+		{"eA" + strings.Repeat("x", 1022), "eA\u030C" + strings.Repeat("x", 1022)},
+		// Still 1024 clusters: a+caron moves on by 10 and wraps around to a+ogonek
+		{"ea\u030C" + strings.Repeat("x", 1022), "ea\u0328" + strings.Repeat("x", 1022)},
+	}
+	for _, tt := range tests {
+		got := applyAccentReplacementLogicToPejelagarto(tt.input)
+		if got != tt.want {
+			t.Errorf("applyAccentReplacementLogicToPejelagarto(%q+x...) = %q+x..., want %q+x...",
+				strings.TrimRight(tt.input, "x"), strings.TrimRight(got, "x"), strings.TrimRight(tt.want, "x"))
+		}
+		if reversed := applyAccentReplacementLogicFromPejelagarto(got); reversed != tt.input {
+			t.Errorf("applyAccentReplacementLogicFromPejelagarto(%q+x...) = %q+x..., want %q+x...",
+				strings.TrimRight(got, "x"), strings.TrimRight(reversed, "x"), strings.TrimRight(tt.input, "x"))
+		}
+	}
+}
+
+// TestLegacyAccents verifies the accents of texts written before the two rune forms joined the wheels
+func TestLegacyAccents(t *testing.T) {
+	tests := []struct {
+		pejelagarto string
+		want        string
+	}{
+		// As written then: the second vowel wrapped around the one rune wheel from ă to a
+		{"ăa", "ăă"},
+		{"naīve cafê", "naïve café"},
+		{"ŶỸ", "ŶŶ"},
+		{"éeè", "éee"},
+	}
+	wheels := currentRules(t).wheels
+	for _, tt := range tests {
+		if got := wheels.legacyApplyFromPejelagarto(tt.pejelagarto); got != tt.want {
+			t.Errorf("legacyApplyFromPejelagarto(%q) = %q, want %q", tt.pejelagarto, got, tt.want)
+		}
+	}
+}
+
 // FuzzApplyPunctuationReplacements tests punctuation replacement reversibility
 func FuzzApplyPunctuationReplacements(f *testing.F) {
 	// Seed corpus with basic cases
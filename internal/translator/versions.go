@@ -0,0 +1,373 @@
+package translator
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Ruleset versions
+// Any edit to the maps or the wheels changes how every text decodes, so the output of the current
+// dialect carries its version: RulesetVersionMarker followed by the hexadecimal digits of the
+// ruleset's Fingerprint, hidden with the timestamp characters. The decoder looks the fingerprint up
+// among the known versions (the current dialect, the archive embedded from rulesets/ and those
+// registered with RegisterRulesetVersion) and decodes with the matching one. Texts with an unknown
+// version are decoded with the translator's own dialect; texts without one were written before
+// versions existed and are decoded as they were written then (see legacyText)
+// Translators with their own Options.Ruleset or Glossary hide no version: their texts already need
+// that dialect to be read back, and the fingerprint of a private dialect would help guess its passphrase
+// The names of the custom stages of Options.Stages follow the fingerprint, so that a decoder with
+// other stages refuses the text (ErrStageMismatch) instead of misreading it; translators with their
+// own dialect then hide privateRulesetVersion in place of the fingerprint
+// Migrate re-encodes a text from the version it was written with to the translator's dialect
+
+// RulesetVersionMarker announces the fingerprint of the dialect that wrote a text
+var RulesetVersionMarker = "⏨"
+
+// RulesetVersionDigitIndex holds the 16 digits of the fingerprint
+var RulesetVersionDigitIndex = []string{
+	"␀", "␁", "␂", "␃", "␄", "␅", "␆", "␇",
+	"␈", "␉", "␊", "␋", "␌", "␍", "␎", "␏",
+}
+
+// rulesetFingerprintBytes is the length of the fingerprint, two digits per byte
+const rulesetFingerprintBytes = 4
+
+// privateRulesetVersion stands for the fingerprint of a translator's own dialect, hidden only to
+// carry the names of its custom stages
+const privateRulesetVersion = "00000000"
+
+var (
+	// ErrNoRulesetVersion is returned by Migrate for a text without a version and no fallback dialect
+	ErrNoRulesetVersion = errors.New("no embedded ruleset version")
+	// ErrUnknownRulesetVersion is returned by Migrate for a version missing from the known rulesets
+	ErrUnknownRulesetVersion = errors.New("unknown ruleset version")
+)
+
+//go:embed rulesets
+var rulesetArchive embed.FS
+
+// Marshal encodes the ruleset as a complete ruleset file in the given format ("json", "yaml" or "yml")
+// Every section is written, so the file does not depend on the built-in dialect it would inherit from
+func (rs *Ruleset) Marshal(format string) ([]byte, error) {
+	file := rulesetFile{
+		Name:                 rs.Name,
+		Conjunctions:         rs.ConjunctionMap,
+		Letters:              rs.LetterMap,
+		CyrillicConjunctions: rs.CyrillicConjunctionMap,
+		CyrillicLetters:      rs.CyrillicLetterMap,
+		GreekConjunctions:    rs.GreekConjunctionMap,
+		GreekLetters:         rs.GreekLetterMap,
+		Punctuation:          rs.PunctuationMap,
+		AccentWheels:         wheelSection(rs.OneRuneAccentsWheel),
+		TwoRuneAccentWheels:  wheelSection(rs.TwoRunesAccentsWheel),
+		ConsonantWheels:      wheelSection(rs.ConsonantWheel),
+	}
+	file.EscapeChars = &struct {
+		Internal string `json:"internal" yaml:"internal"`
+		Output   string `json:"output" yaml:"output"`
+	}{string(rs.InternalEscapeChar), string(rs.OutputEscapeChar)}
+
+	switch format {
+	case "json":
+		return json.MarshalIndent(file, "", "  ")
+	case "yaml", "yml":
+		return yaml.Marshal(file)
+	}
+	return nil, fmt.Errorf("unsupported ruleset format %q (expected json or yaml)", format)
+}
+
+// wheelSection converts rune-keyed wheels into the string-keyed section of a ruleset file
+func wheelSection(wheels map[rune][]string) map[string][]string {
+	section := make(map[string][]string, len(wheels))
+	for key, forms := range wheels {
+		section[string(key)] = forms
+	}
+	return section
+}
+
+// Fingerprint identifies the rules of a dialect: the first bytes of the SHA-256 of its JSON form,
+// in hexadecimal. The name is left out, renaming a dialect does not change how it translates
+func (rs *Ruleset) Fingerprint() string {
+	unnamed := *rs
+	unnamed.Name = ""
+	// JSON sorts map keys, so equal rulesets always give the same bytes
+	data, err := unnamed.Marshal("json")
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:rulesetFingerprintBytes])
+}
+
+// rulesetVersions holds the known versions by fingerprint, the archive being loaded on first use
+var rulesetVersions struct {
+	sync.Mutex
+	loaded   bool
+	rulesets map[string]*Ruleset
+	compiled map[string]*compiledRules
+}
+
+// RegisterRulesetVersion validates an older dialect and makes its texts decodable
+func RegisterRulesetVersion(rs *Ruleset) error {
+	if err := rs.Validate(); err != nil {
+		return err
+	}
+	rulesetVersions.Lock()
+	defer rulesetVersions.Unlock()
+	loadRulesetArchive()
+	rulesetVersions.rulesets[rs.Fingerprint()] = rs.Clone()
+	return nil
+}
+
+// LoadRulesetHistory registers every .json, .yaml and .yml ruleset file of a directory as an older version
+func LoadRulesetHistory(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading ruleset history: %w", err)
+	}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+		rs, err := LoadRuleset(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if err := RegisterRulesetVersion(rs); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// ArchiveRuleset writes a dialect to dir as <fingerprint>.json, the form the archive and
+// LoadRulesetHistory read, and returns the path of the file
+func ArchiveRuleset(rs *Ruleset, dir string) (string, error) {
+	data, err := rs.Marshal("json")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, rs.Fingerprint()+".json")
+	if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("archiving ruleset: %w", err)
+	}
+	return file, nil
+}
+
+// RulesetVersion returns a copy of the known dialect with the given fingerprint
+func RulesetVersion(fingerprint string) (*Ruleset, bool) {
+	if current := CurrentRuleset(); current.Fingerprint() == fingerprint {
+		return current, true
+	}
+	rulesetVersions.Lock()
+	defer rulesetVersions.Unlock()
+	loadRulesetArchive()
+	rs, ok := rulesetVersions.rulesets[fingerprint]
+	if !ok {
+		return nil, false
+	}
+	return rs.Clone(), true
+}
+
+// RulesetVersions returns the fingerprints of the archived and registered versions, sorted
+func RulesetVersions() []string {
+	rulesetVersions.Lock()
+	defer rulesetVersions.Unlock()
+	loadRulesetArchive()
+	fingerprints := make([]string, 0, len(rulesetVersions.rulesets))
+	for fingerprint := range rulesetVersions.rulesets {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	sort.Strings(fingerprints)
+	return fingerprints
+}
+
+// loadRulesetArchive reads the embedded archive once; the caller holds the lock
+// Archived files are written by ArchiveRuleset and named after their fingerprint
+func loadRulesetArchive() {
+	if rulesetVersions.loaded {
+		return
+	}
+	rulesetVersions.loaded = true
+	rulesetVersions.rulesets = make(map[string]*Ruleset)
+	rulesetVersions.compiled = make(map[string]*compiledRules)
+	entries, err := rulesetArchive.ReadDir("rulesets")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := rulesetArchive.ReadFile(path.Join("rulesets", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		rs, err := ParseRuleset(data, strings.TrimPrefix(path.Ext(entry.Name()), "."))
+		if err != nil {
+			panic(fmt.Errorf("archived ruleset %s: %w", entry.Name(), err))
+		}
+		rulesetVersions.rulesets[rs.Fingerprint()] = rs
+	}
+}
+
+// compiledRulesetVersion returns the compiled rules of a known version, nil if it is unknown
+func compiledRulesetVersion(fingerprint string) *compiledRules {
+	if current, err := currentCompiledRules(); err == nil && current.fingerprint == fingerprint {
+		return current
+	}
+	rulesetVersions.Lock()
+	defer rulesetVersions.Unlock()
+	loadRulesetArchive()
+	if compiled, ok := rulesetVersions.compiled[fingerprint]; ok {
+		return compiled
+	}
+	rs, ok := rulesetVersions.rulesets[fingerprint]
+	if !ok {
+		return nil
+	}
+	compiled := compileRules(rs, nil)
+	rulesetVersions.compiled[fingerprint] = compiled
+	return compiled
+}
+
+// rulesetVersionChars returns the characters hiding a fingerprint and the names of the custom stages,
+// joined with commas, two digits per byte
+func rulesetVersionChars(fingerprint string, stages []string) []string {
+	digits := fingerprint + hex.EncodeToString([]byte(strings.Join(stages, ",")))
+	chars := []string{RulesetVersionMarker}
+	for _, digit := range digits {
+		value := strings.IndexRune("0123456789abcdef", digit)
+		chars = append(chars, RulesetVersionDigitIndex[value])
+	}
+	return chars
+}
+
+// readRulesetVersion returns the fingerprint hidden in the timestamp characters of a text, "" if there
+// is none or it is damaged
+func readRulesetVersion(hidden string) string {
+	fingerprint, _, _ := readHiddenVersion(hidden)
+	return fingerprint
+}
+
+// readHiddenVersion returns the fingerprint and the custom stage names hidden in the timestamp
+// characters of a text, and false if there is no version or it is damaged
+func readHiddenVersion(hidden string) (fingerprint string, stages []string, ok bool) {
+	if strings.Count(hidden, RulesetVersionMarker) != 1 {
+		return "", nil, false
+	}
+	digitValues := make(map[rune]int, len(RulesetVersionDigitIndex))
+	for i, digit := range RulesetVersionDigitIndex {
+		digitValues[[]rune(digit)[0]] = i
+	}
+	var digits strings.Builder
+	for _, r := range hidden[strings.Index(hidden, RulesetVersionMarker):] {
+		if value, isDigit := digitValues[r]; isDigit {
+			digits.WriteByte("0123456789abcdef"[value])
+		}
+	}
+	if digits.Len() < 2*rulesetFingerprintBytes {
+		return "", nil, false
+	}
+	fingerprint = digits.String()[:2*rulesetFingerprintBytes]
+	names, err := hex.DecodeString(digits.String()[2*rulesetFingerprintBytes:])
+	if err != nil {
+		return "", nil, false
+	}
+	if len(names) > 0 {
+		stages = strings.Split(string(names), ",")
+	}
+	return fingerprint, stages, true
+}
+
+// legacyRulesetVersion is the fingerprint of the archived dialect of the texts written before versions
+const legacyRulesetVersion = "d30fe0cf"
+
+// decodingRules returns the dialect a Pejelagarto text is decoded with: the known version it
+// carries, or the legacyRulesetVersion if it has none, when the translator uses the current
+// dialect; the translator's own otherwise
+func (t *Translator) decodingRules(input string) (*compiledRules, error) {
+	rules, err := t.rules()
+	if err != nil || t.compiled != nil || t.opts.DisableTimestamp {
+		return rules, err
+	}
+	hidden, _, _ := splitHiddenTimestampChars(input)
+	version := readRulesetVersion(hidden)
+	if t.legacyText(input) {
+		version = legacyRulesetVersion
+	}
+	if version != "" && version != rules.fingerprint {
+		if compiled := compiledRulesetVersion(version); compiled != nil {
+			return compiled, nil
+		}
+	}
+	return rules, nil
+}
+
+// legacyText reports whether a text was written before dialect versions: it hides a timestamp but
+// no version, and the translator uses the current dialect. Such texts are decoded as they were
+// written then: with the dialect, the accent wheels and the number format of that time and without
+// the consonant stage
+func (t *Translator) legacyText(input string) bool {
+	if t.compiled != nil || t.opts.DisableTimestamp {
+		return false
+	}
+	hidden, _, _ := splitHiddenTimestampChars(input)
+	return !strings.Contains(hidden, RulesetVersionMarker) && RemoveTimestampSpecialCharacters(hidden) != hidden
+}
+
+// Migrate re-encodes a Pejelagarto text written with a known version of the dialect into the
+// translator's dialect, keeping its hidden timestamp, locality, lossless mode and metadata
+// Texts without a version are read with from; with from nil they are read with the dialect of the
+// time before versions, and texts hiding no timestamp at all return ErrNoRulesetVersion
+// The stage options of the translator must be those the text was written with
+func (t *Translator) Migrate(input string, from *Ruleset) (string, error) {
+	if t.opts.DisableTimestamp {
+		return "", fmt.Errorf("migrating: %w, the timestamp stage is disabled", ErrNoRulesetVersion)
+	}
+	hidden, _, lossless := splitHiddenTimestampChars(input)
+	if version := readRulesetVersion(hidden); version != "" {
+		rs, ok := RulesetVersion(version)
+		if !ok {
+			return "", fmt.Errorf("migrating: %w %s", ErrUnknownRulesetVersion, version)
+		}
+		from = rs
+	} else if from == nil && RemoveTimestampSpecialCharacters(hidden) == hidden {
+		return "", ErrNoRulesetVersion
+	}
+	metadata, err := readMetadata(hidden)
+	if err != nil && !errors.Is(err, ErrNoMetadata) {
+		return "", fmt.Errorf("migrating: %w", err)
+	}
+
+	// The reader has no version to hide, so it is not built by New
+	// Without from, a text written before versions is read as it was written (see legacyText)
+	readerOpts := t.opts
+	readerOpts.Ruleset, readerOpts.Glossary, readerOpts.Key = from, nil, nil
+	reader := &Translator{opts: readerOpts, stages: t.stages}
+	if from != nil {
+		if err := from.Validate(); err != nil {
+			return "", fmt.Errorf("migrating: %w", err)
+		}
+		reader.compiled = compileRules(from.Clone(), nil)
+	}
+	human := reader.FromPejelagarto(input)
+
+	writerOpts := t.opts
+	writerOpts.Locality = reader.locality(input)
+	writerOpts.Lossless = writerOpts.Lossless || lossless
+	writerOpts.Metadata = metadata
+	return New(writerOpts).ToPejelagartoContext(context.Background(), human)
+}
@@ -0,0 +1,176 @@
+package translator
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// useRulesetForTest makes rs the current dialect until the test ends
+func useRulesetForTest(tb testing.TB, rs *Ruleset) {
+	tb.Helper()
+	previous := CurrentRuleset()
+	if err := UseRuleset(rs); err != nil {
+		tb.Fatalf("UseRuleset: %v", err)
+	}
+	tb.Cleanup(func() { UseRuleset(previous) })
+}
+
+// withRulesetVersion replaces the version hidden in a text
+func withRulesetVersion(text, fingerprint string) string {
+	versionChars := strings.Join(append([]string{RulesetVersionMarker}, RulesetVersionDigitIndex...), "")
+	text = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(versionChars, r) {
+			return -1
+		}
+		return r
+	}, text)
+	return text + strings.Join(rulesetVersionChars(fingerprint, nil), "")
+}
+
+// FuzzMigrate tests that texts written before a dialect change still decode and migrate
+func FuzzMigrate(f *testing.F) {
+	// Seed corpus with basic cases
+	f.Add("Hello world, the shell is here! 3.14")
+	f.Add("Привет мир\nCafé ΓΕΙΑ")
+	old := fixedTestTranslator(Options{})
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+		input = RemoveTimestampSpecialCharacters(input)
+		pejelagarto := old.ToPejelagarto(input)
+		want := old.FromPejelagarto(pejelagarto)
+
+		useRulesetForTest(t, generatedRuleset(t, "a later version"))
+		current := New(Options{})
+		if reversed := current.FromPejelagarto(pejelagarto); reversed != want {
+			t.Errorf("decoding with the archived version failed\nInput:       %q\nPejelagarto: %q\nReversed:    %q", want, pejelagarto, reversed)
+		}
+		migrated, err := current.Migrate(pejelagarto, nil)
+		if err != nil {
+			t.Fatalf("Migrate: %v", err)
+		}
+		if reversed := current.FromPejelagarto(migrated); reversed != want {
+			t.Errorf("migration failed\nInput:    %q\nMigrated: %q\nReversed: %q", want, migrated, reversed)
+		}
+	})
+}
+
+// TestRulesetHistory verifies the built-in dialect is archived, so its texts decode after it changes
+func TestRulesetHistory(t *testing.T) {
+	fingerprint := DefaultRuleset().Fingerprint()
+	if rs, ok := RulesetVersion(fingerprint); !ok || rs.Fingerprint() != fingerprint {
+		t.Fatalf("the built-in dialect %s is not archived: run `pejelagarto-translator -archive_ruleset internal/translator/rulesets` "+
+			"and keep the files of the older versions", fingerprint)
+	}
+	files, err := filepath.Glob(filepath.Join("rulesets", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		rs, err := LoadRuleset(file)
+		if err != nil {
+			t.Fatalf("archived ruleset: %v", err)
+		}
+		if want := rs.Fingerprint() + ".json"; filepath.Base(file) != want {
+			t.Errorf("archived ruleset %s should be named %s", file, want)
+		}
+	}
+
+	renamed := DefaultRuleset()
+	renamed.Name = "renamed"
+	if renamed.Fingerprint() != fingerprint {
+		t.Errorf("renaming a dialect changed its fingerprint")
+	}
+	data, err := DefaultRuleset().Marshal("yaml")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if parsed, err := ParseRuleset(data, "yaml"); err != nil || parsed.Fingerprint() != fingerprint {
+		t.Errorf("ParseRuleset(Marshal()) = %v, %v, want fingerprint %s", parsed, err, fingerprint)
+	}
+
+	// A version registered from a directory decodes its texts once the current dialect has changed
+	older := generatedRuleset(t, "an older version")
+	dir := t.TempDir()
+	if _, err := ArchiveRuleset(older, dir); err != nil {
+		t.Fatalf("ArchiveRuleset: %v", err)
+	}
+	if err := LoadRulesetHistory(dir); err != nil {
+		t.Fatalf("LoadRulesetHistory: %v", err)
+	}
+	useRulesetForTest(t, older)
+	input := "Hello world, the shell is here"
+	pejelagarto := New(Options{}).ToPejelagarto(input)
+	if version := readRulesetVersion(pejelagarto); version != older.Fingerprint() {
+		t.Errorf("hidden version = %q, want %q", version, older.Fingerprint())
+	}
+	useRulesetForTest(t, DefaultRuleset())
+	if reversed, _ := removeISO8601timestamp(New(Options{}).FromPejelagarto(pejelagarto)); reversed != input {
+		t.Errorf("decoding a registered version = %q, want %q", reversed, input)
+	}
+
+	unknown := withRulesetVersion(pejelagarto, "00000000")
+	if diagnostics := New(Options{}).Inspect(unknown); len(diagnostics) != 1 || diagnostics[0].Code != DiagnosticRulesetVersion {
+		t.Errorf("Inspect(unknown version) = %+v, want a %s warning", diagnostics, DiagnosticRulesetVersion)
+	}
+
+	// Private dialects hide no version
+	if version := readRulesetVersion(New(Options{Ruleset: older}).ToPejelagarto(input)); version != "" {
+		t.Errorf("a translator with its own dialect hid version %q", version)
+	}
+}
+
+// TestLegacyRulesetVersion verifies texts written before versions decode with the archived dialect of that time
+func TestLegacyRulesetVersion(t *testing.T) {
+	if _, ok := RulesetVersion(legacyRulesetVersion); !ok {
+		t.Fatalf("the dialect of the texts without a version, %s, is not archived", legacyRulesetVersion)
+	}
+
+	// Translated before versions, with the timestamp hidden
+	tests := []struct {
+		pejelagarto string
+		want        string
+	}{
+		{"\u230F'EL\u1EBD\uA4FC vaOcr\uFE71 pKiem\u02B9\u2DFB", "the quick brown"},
+		{"\u230F'aRaKa\u060C\uA4FC E\u00ECkgF\u00A1\uFE71 173 \u201063\u02B9 007 131 \u2010141\u2DFB", "Hello, World! 123 -45 007 89 -78"},
+		{"oT\u00AD\u00AD\u00AD'S u \u301Et\u1E82st\u301E\uFE30 'ele 'fil\u00F2\u230F suof 'arak\u204F\uA4FC 'xS'leg\uFE71 'jcut\u02B9 'ady\u00AD'..\u2DFB",
+			"It's a \"test\": the fran said hola; shell chat leg."},
+	}
+	tr := New(Options{})
+	for _, tt := range tests {
+		if got, _ := removeISO8601timestamp(tr.FromPejelagarto(tt.pejelagarto)); got != tt.want {
+			t.Errorf("FromPejelagarto(baseline %q) = %q", tt.want, got)
+		}
+		migrated, err := tr.Migrate(tt.pejelagarto, nil)
+		if err != nil {
+			t.Fatalf("Migrate(baseline %q): %v", tt.want, err)
+		}
+		if readRulesetVersion(migrated) != DefaultRuleset().Fingerprint() {
+			t.Errorf("Migrate(baseline %q) hid no current version", tt.want)
+		}
+		if got, _ := removeISO8601timestamp(tr.FromPejelagarto(migrated)); got != tt.want {
+			t.Errorf("FromPejelagarto(Migrate(baseline %q)) = %q", tt.want, got)
+		}
+	}
+}
+
+// TestMigrateErrors verifies texts hiding no timestamp or an unknown version are refused unless a dialect is given
+func TestMigrateErrors(t *testing.T) {
+	tr := New(Options{})
+	if _, err := tr.Migrate("Hello world", nil); !errors.Is(err, ErrNoRulesetVersion) {
+		t.Errorf("Migrate(no timestamp, nil) error = %v, want ErrNoRulesetVersion", err)
+	}
+	unversioned := New(Options{Ruleset: DefaultRuleset()}).ToPejelagarto("Hello world")
+	if migrated, err := tr.Migrate(unversioned, DefaultRuleset()); err != nil || readRulesetVersion(migrated) != DefaultRuleset().Fingerprint() {
+		t.Errorf("Migrate(unversioned, default) = %q, %v", migrated, err)
+	}
+
+	unknown := withRulesetVersion(tr.ToPejelagarto("Hello world"), "00000000")
+	if _, err := tr.Migrate(unknown, nil); !errors.Is(err, ErrUnknownRulesetVersion) {
+		t.Errorf("Migrate(unknown) error = %v, want ErrUnknownRulesetVersion", err)
+	}
+}
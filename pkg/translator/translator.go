@@ -2,25 +2,89 @@
 package translator
 
 import (
+	"encoding/json"
+	"errors"
+	"time"
+
 	internalTranslator "pejelagarto-translator/internal/translator"
 )
 
+// Options selects which translation stages run
+// gomobile only binds basic field types, so the timestamp is an RFC 3339 string and the metadata JSON
+type Options struct {
+	DisableNumbers         bool
+	DisablePunctuation     bool
+	DisableMapReplacements bool
+	DisableAccents         bool
+	DisableConsonants      bool
+	DisableCase            bool
+	DisableNormalization   bool
+	DisableTimestamp       bool
+	Timestamp              string // RFC 3339 time to embed, empty for the current time
+	Locality               string // "sentence" or "paragraph" to keep edits local, empty for the whole text
+	Lossless               bool   // escape the timestamp characters of the input instead of removing them
+	Metadata               string // JSON object of strings to hide in the output, empty for none
+}
+
+// NewOptions returns options with every stage enabled
+func NewOptions() *Options {
+	return &Options{}
+}
+
 // Translator is a simple wrapper for translation operations
-type Translator struct{}
+type Translator struct {
+	translator *internalTranslator.Translator
+}
 
 // New creates a new Translator instance
 func New() *Translator {
-	return &Translator{}
+	return &Translator{translator: internalTranslator.New(internalTranslator.Options{})}
+}
+
+// NewWithOptions creates a Translator using the given options
+func NewWithOptions(opts *Options) (*Translator, error) {
+	internalOpts := internalTranslator.Options{
+		DisableNumbers:         opts.DisableNumbers,
+		DisablePunctuation:     opts.DisablePunctuation,
+		DisableMapReplacements: opts.DisableMapReplacements,
+		DisableAccents:         opts.DisableAccents,
+		DisableConsonants:      opts.DisableConsonants,
+		DisableCase:            opts.DisableCase,
+		DisableNormalization:   opts.DisableNormalization,
+		DisableTimestamp:       opts.DisableTimestamp,
+		Locality:               internalTranslator.Locality(opts.Locality),
+		Lossless:               opts.Lossless,
+	}
+	if opts.Timestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339, opts.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		internalOpts.Timestamp = timestamp
+	}
+	if opts.Metadata != "" {
+		if err := json.Unmarshal([]byte(opts.Metadata), &internalOpts.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return &Translator{translator: internalTranslator.New(internalOpts)}, nil
 }
 
 // TranslateToPejelagarto translates English text to Pejelagarto language
 func (t *Translator) TranslateToPejelagarto(text string) string {
-	return internalTranslator.TranslateToPejelagarto(text)
+	return t.translator.ToPejelagarto(text)
 }
 
 // TranslateFromPejelagarto translates Pejelagarto text to English
 func (t *Translator) TranslateFromPejelagarto(text string) string {
-	return internalTranslator.TranslateFromPejelagarto(text)
+	return t.translator.FromPejelagarto(text)
+}
+
+// RecoverFromPejelagarto reads Pejelagarto typed without its accents or hidden characters,
+// returning the candidate readings as a JSON array, best first
+func (t *Translator) RecoverFromPejelagarto(text string) (string, error) {
+	encoded, err := json.Marshal(t.translator.RecoverFromPejelagarto(text))
+	return string(encoded), err
 }
 
 // Package-level functions for direct calls
@@ -31,3 +95,26 @@ func TranslateToPejelagarto(text string) string {
 func TranslateFromPejelagarto(text string) string {
 	return internalTranslator.TranslateFromPejelagarto(text)
 }
+
+// TranslateToPejelagartoWithKey translates English text to the private dialect generated from key
+func TranslateToPejelagartoWithKey(text string, key string) string {
+	return internalTranslator.TranslateToPejelagartoWithKey(text, key)
+}
+
+// TranslateFromPejelagartoWithKey translates text in the private dialect generated from key to English
+func TranslateFromPejelagartoWithKey(text string, key string) string {
+	return internalTranslator.TranslateFromPejelagartoWithKey(text, key)
+}
+
+// ExtractMetadata returns the metadata hidden in Pejelagarto text as a JSON object,
+// "{}" when there is none
+func ExtractMetadata(text string) (string, error) {
+	metadata, err := internalTranslator.ExtractMetadata(text)
+	if errors.Is(err, internalTranslator.ErrNoMetadata) {
+		metadata = internalTranslator.Metadata{}
+	} else if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(metadata)
+	return string(encoded), err
+}
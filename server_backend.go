@@ -7,17 +7,28 @@ package main
 // When building with -tags frontendserver, this file is excluded and server_frontend.go's main() is used instead
 
 import (
+	"container/list"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"os/exec"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -66,6 +77,251 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, html)
 }
 
+// dialectHeader carries the passphrase of a private dialect, kept out of URLs and access logs
+const dialectHeader = "X-Pejelagarto-Dialect"
+
+// maxCachedDialects bounds the dialects kept by generatedDialect
+const maxCachedDialects = 64
+
+// cachedDialect is a dialect generated for a request, keyed by the SHA-256 of its passphrase so
+// that the passphrase itself is not kept
+type cachedDialect struct {
+	hash    [sha256.Size]byte
+	ruleset *translator.Ruleset
+}
+
+// dialects holds the most recently used dialects, generating one takes milliseconds
+var dialects = struct {
+	sync.Mutex
+	order  *list.List // of cachedDialect, most recently used first
+	byHash map[[sha256.Size]byte]*list.Element
+}{order: list.New(), byHash: make(map[[sha256.Size]byte]*list.Element)}
+
+// generatedDialect returns translator.GenerateRuleset(passphrase), from the cache when it holds it
+// The ruleset is shared between requests: translator.New clones it, nothing else may change it
+func generatedDialect(passphrase string) (*translator.Ruleset, error) {
+	hash := sha256.Sum256([]byte(passphrase))
+	dialects.Lock()
+	if element, cached := dialects.byHash[hash]; cached {
+		dialects.order.MoveToFront(element)
+		dialects.Unlock()
+		return element.Value.(cachedDialect).ruleset, nil
+	}
+	dialects.Unlock()
+
+	rs, err := translator.GenerateRuleset(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	dialects.Lock()
+	defer dialects.Unlock()
+	if _, cached := dialects.byHash[hash]; !cached {
+		dialects.byHash[hash] = dialects.order.PushFront(cachedDialect{hash: hash, ruleset: rs})
+		if dialects.order.Len() > maxCachedDialects {
+			oldest := dialects.order.Remove(dialects.order.Back()).(cachedDialect)
+			delete(dialects.byHash, oldest.hash)
+		}
+	}
+	return rs, nil
+}
+
+// translatorFromRequest builds a Translator from the request's query parameters
+// (e.g. /to?accents=false&timestamp=2025-10-19T14:30:00Z&seed=42, see translator.ParseOptions)
+// and the passphrase of the dialectHeader
+func translatorFromRequest(r *http.Request) (*translator.Translator, error) {
+	query := r.URL.Query()
+	// /from?metadata=true asks for the hidden metadata, only a JSON object is metadata to hide
+	if _, err := strconv.ParseBool(query.Get("metadata")); err == nil {
+		query.Del("metadata")
+	}
+	if query.Has("dialect") || query.Has("key") {
+		return nil, fmt.Errorf("option dialect: send the passphrase in the %s header, not in the URL", dialectHeader)
+	}
+	opts, err := translator.ParseOptions(query)
+	if err != nil {
+		return nil, err
+	}
+	opts.Key = signingKey
+	if passphrase := r.Header.Get(dialectHeader); passphrase != "" {
+		if opts.Ruleset, err = generatedDialect(passphrase); err != nil {
+			return nil, fmt.Errorf("option dialect: %w", err)
+		}
+	}
+
+	// ?glossary=<name> adds the pairs registered under that name through /glossary
+	if name := r.URL.Query().Get("glossary"); name != "" {
+		glossaries.Lock()
+		registered, exists := glossaries.byName[name]
+		glossaries.Unlock()
+		if !exists {
+			return nil, fmt.Errorf("option glossary: no glossary named %q", name)
+		}
+		glossary := registered.Glossary
+		if opts.Ruleset != nil {
+			// The pairs were checked against the shared dialect, check them again against the private one
+			private := translator.NewGlossary(opts.Ruleset)
+			for word, replacement := range glossary.Entries() {
+				if err := private.Add(word, replacement); err != nil {
+					return nil, fmt.Errorf("option glossary: %w", err)
+				}
+			}
+			glossary = private
+		}
+		opts.Glossary = glossary
+	}
+	return translator.New(opts), nil
+}
+
+// Limits of the glossaries registered through /glossary, which live in memory until the server stops
+const (
+	maxGlossaries        = 100
+	maxGlossaryEntries   = 1000
+	maxGlossaryBodyBytes = 64 << 10
+	glossaryKeyHeader    = "X-Pejelagarto-Glossary-Key"
+)
+
+// registeredGlossary is a glossary registered through /glossary and the key that modifies it
+type registeredGlossary struct {
+	*translator.Glossary
+	key string
+}
+
+// glossaries holds the glossaries registered through /glossary, by name
+var glossaries = struct {
+	sync.Mutex
+	byName map[string]registeredGlossary
+}{byName: make(map[string]registeredGlossary)}
+
+// newGlossaryKey returns a random key for a new glossary
+func newGlossaryKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// HTTP handler for registering glossary pairs
+// GET /glossary?name=<name> returns the pairs as a JSON object, POST adds the pairs of a JSON object
+// body and responds with the accepted pairs and the rejected ones with their reasons, and DELETE
+// removes one pair (&word=<word>) or the whole glossary
+// The POST that creates a glossary responds with its key in the glossaryKeyHeader; modifying or
+// deleting it then requires that key in the same header
+func handleGlossary(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "Missing glossary name", http.StatusBadRequest)
+		return
+	}
+	var pairs map[string]string
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxGlossaryBodyBytes)
+		if err := json.NewDecoder(r.Body).Decode(&pairs); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", maxGlossaryBodyBytes), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Request body must be a JSON object of word pairs", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The whole request runs under the lock, so the limits hold against concurrent requests
+	glossaries.Lock()
+	defer glossaries.Unlock()
+	glossary, exists := glossaries.byName[name]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.Error(w, fmt.Sprintf("No glossary named %q", name), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(glossary.Entries())
+
+	case http.MethodPost:
+		if !exists {
+			if len(glossaries.byName) >= maxGlossaries {
+				http.Error(w, fmt.Sprintf("At most %d glossaries can be registered", maxGlossaries), http.StatusInsufficientStorage)
+				return
+			}
+			key, err := newGlossaryKey()
+			if err != nil {
+				http.Error(w, "Failed to create the glossary key", http.StatusInternalServerError)
+				return
+			}
+			glossary = registeredGlossary{Glossary: translator.NewGlossary(nil), key: key}
+			glossaries.byName[name] = glossary
+			w.Header().Set(glossaryKeyHeader, key)
+		} else if !glossaryKeyMatches(r, glossary) {
+			http.Error(w, fmt.Sprintf("Modifying glossary %q requires its key in the %s header", name, glossaryKeyHeader), http.StatusForbidden)
+			return
+		}
+
+		words := make([]string, 0, len(pairs))
+		for word := range pairs {
+			words = append(words, word)
+		}
+		sort.Strings(words)
+
+		accepted := make(map[string]string)
+		rejected := []*translator.GlossaryError{}
+		entries := glossary.Entries()
+		for _, word := range words {
+			if _, replaced := entries[strings.ToLower(word)]; !replaced && len(entries) >= maxGlossaryEntries {
+				rejected = append(rejected, &translator.GlossaryError{Word: word, Replacement: pairs[word],
+					Reason: fmt.Sprintf("the glossary already holds %d pairs", maxGlossaryEntries)})
+				continue
+			}
+			if err := glossary.Add(word, pairs[word]); err != nil {
+				var glossaryErr *translator.GlossaryError
+				if !errors.As(err, &glossaryErr) {
+					glossaryErr = &translator.GlossaryError{Word: word, Replacement: pairs[word], Reason: err.Error()}
+				}
+				rejected = append(rejected, glossaryErr)
+				continue
+			}
+			accepted[strings.ToLower(word)] = strings.ToLower(pairs[word])
+			entries[strings.ToLower(word)] = strings.ToLower(pairs[word])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": accepted,
+			"rejected": rejected,
+		})
+
+	case http.MethodDelete:
+		if !exists {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !glossaryKeyMatches(r, glossary) {
+			http.Error(w, fmt.Sprintf("Deleting from glossary %q requires its key in the %s header", name, glossaryKeyHeader), http.StatusForbidden)
+			return
+		}
+		if word := r.URL.Query().Get("word"); word != "" {
+			glossary.Remove(word)
+		} else {
+			delete(glossaries.byName, name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// glossaryKeyMatches reports whether a request carries the key of a glossary
+func glossaryKeyMatches(r *http.Request, glossary registeredGlossary) bool {
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(glossaryKeyHeader)), []byte(glossary.key)) == 1
+}
+
+// signingKey signs /to output and checks /from?verify=1 input, set by -signing_key_file
+var signingKey []byte
+
 // HTTP handler for translating to Pejelagarto
 func handleTranslateTo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -73,6 +329,12 @@ func handleTranslateTo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tr, err := translatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Error reading request body", http.StatusBadRequest)
@@ -80,7 +342,28 @@ func handleTranslateTo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	input := string(body)
-	result := translator.TranslateToPejelagarto(input)
+
+	// /to?alignment=true returns JSON holding the translation as text, its spans and its gloss
+	if aligned, _ := strconv.ParseBool(r.URL.Query().Get("alignment")); aligned {
+		alignment, err := tr.TranslateWithAlignmentContext(r.Context(), input, translator.DirectionToPejelagarto)
+		if err != nil {
+			writeTranslationError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"text":  alignment.Target,
+			"spans": alignment.Spans,
+			"gloss": alignment.Gloss(),
+		})
+		return
+	}
+
+	result, err := tr.ToPejelagartoContext(r.Context(), input)
+	if err != nil {
+		writeTranslationError(w, r, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	fmt.Fprint(w, result)
@@ -93,19 +376,231 @@ func handleTranslateFrom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tr, err := translatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "Error reading request body", http.StatusBadRequest)
 		return
 	}
 
+	warnings, _ := strconv.ParseBool(r.URL.Query().Get("warnings"))
+	verify, _ := strconv.ParseBool(r.URL.Query().Get("verify"))
+	aligned, _ := strconv.ParseBool(r.URL.Query().Get("alignment"))
+	withMetadata, _ := strconv.ParseBool(r.URL.Query().Get("metadata"))
+	if verify && len(signingKey) == 0 {
+		http.Error(w, "verify requires the server to be started with -signing_key_file", http.StatusBadRequest)
+		return
+	}
+
 	input := string(body)
-	result := translator.TranslateFromPejelagarto(input)
+	result, verification, err := tr.FromPejelagartoVerifiedContext(r.Context(), input)
+	if err != nil {
+		writeTranslationError(w, r, err)
+		return
+	}
+
+	// /from?warnings=true adds the diagnostics of the input, /from?verify=1 whether it is
+	// authentic, tampered or unsigned, /from?alignment=true the spans and gloss of the
+	// translation and /from?metadata=true the hidden metadata (empty when there is none or it is
+	// damaged, see warnings); all return JSON holding the translation as text
+	if warnings || verify || aligned || withMetadata {
+		response := map[string]interface{}{"text": result}
+		if aligned {
+			alignment, err := tr.TranslateWithAlignmentContext(r.Context(), input, translator.DirectionFromPejelagarto)
+			if err != nil {
+				writeTranslationError(w, r, err)
+				return
+			}
+			response["spans"] = alignment.Spans
+			response["gloss"] = alignment.Gloss()
+		}
+		if warnings {
+			diagnostics := tr.Inspect(input)
+			if diagnostics == nil {
+				diagnostics = []translator.Diagnostic{}
+			}
+			response["warnings"] = diagnostics
+		}
+		if verify {
+			response["verification"] = verification
+		}
+		if withMetadata {
+			metadata, err := translator.ExtractMetadata(input)
+			if err != nil {
+				metadata = translator.Metadata{}
+			}
+			response["metadata"] = metadata
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	fmt.Fprint(w, result)
 }
 
+// HTTP handler for translating in the detected direction
+// Responds with JSON holding the translation, the detected direction ("to" or "from") and its confidence
+func handleTranslateAuto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tr, err := translatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	result, detection, err := tr.TranslateAutoContext(r.Context(), string(body))
+	if err != nil {
+		writeTranslationError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Text       string               `json:"text"`
+		Direction  translator.Direction `json:"direction"`
+		Confidence float64              `json:"confidence"`
+		Signals    []translator.Signal  `json:"signals"`
+	}{result, detection.Direction, detection.Confidence, detection.Signals})
+}
+
+// HTTP handler for reading hand-typed Pejelagarto, without its accents or hidden characters
+// Responds with a JSON array of the candidate readings, best first
+func handleRecover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tr, err := translatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	candidates, err := tr.RecoverFromPejelagartoContext(r.Context(), string(body))
+	if err != nil {
+		writeTranslationError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// HTTP handler for streaming translation to Pejelagarto
+// The request body is translated chunk by chunk and written back as framed Pejelagarto,
+// so arbitrarily large documents never have to be held in memory
+func handleStreamTo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tr, err := translatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := tr.StreamToPejelagarto(w, r.Body, 0); err != nil {
+		writeTranslationError(w, r, err)
+	}
+}
+
+// HTTP handler for streaming translation from framed Pejelagarto
+func handleStreamFrom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tr, err := translatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := tr.StreamFromPejelagarto(w, r.Body); err != nil {
+		writeTranslationError(w, r, err)
+	}
+}
+
+// writeTranslationError answers a request whose translation failed with err
+// Invalid streams are the client's fault, anything else is logged
+func writeTranslationError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		// The client went away, nobody is waiting for the result
+		return
+	}
+	if errors.Is(err, translator.ErrInvalidStream) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !config.Obfuscated() {
+		log.Printf("Translation for %s failed: %v", r.URL.Path, err)
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// runStreamTranslation translates stdin to stdout in the given direction ("to" or "from")
+func runStreamTranslation(direction string) error {
+	switch direction {
+	case "to":
+		return translator.TranslateStreamToPejelagarto(os.Stdout, os.Stdin, 0)
+	case "from":
+		return translator.TranslateStreamFromPejelagarto(os.Stdout, os.Stdin)
+	default:
+		return fmt.Errorf("invalid stream direction %q (expected \"to\" or \"from\")", direction)
+	}
+}
+
+// runMigration re-encodes the Pejelagarto text read from stdin into the current dialect on stdout
+// Texts without a ruleset version are read with the ruleset file from, if given
+func runMigration(from string) error {
+	var rs *translator.Ruleset
+	if from != "" {
+		var err error
+		if rs, err = translator.LoadRuleset(from); err != nil {
+			return err
+		}
+	}
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	migrated, err := translator.New(translator.Options{}).Migrate(string(input), rs)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, migrated)
+	return err
+}
+
 // Global variable to store the pronunciation language flag
 
 // getPiperBinaryPath returns the path to the Piper binary
@@ -200,6 +695,39 @@ func validateConstants() error {
 	if err := checkDuplicates(translator.MinuteSpecialCharIndex, "translator.MinuteSpecialCharIndex"); err != nil {
 		return err
 	}
+	if err := checkDuplicates([]string{translator.TimestampV2Marker}, "translator.TimestampV2Marker"); err != nil {
+		return err
+	}
+	if err := checkDuplicates(translator.TimestampV2DigitIndex, "translator.TimestampV2DigitIndex"); err != nil {
+		return err
+	}
+	if err := checkDuplicates([]string{translator.SignatureMarker}, "translator.SignatureMarker"); err != nil {
+		return err
+	}
+	if err := checkDuplicates(translator.SignatureDigitIndex, "translator.SignatureDigitIndex"); err != nil {
+		return err
+	}
+	if err := checkDuplicates([]string{translator.NormalizationMarker}, "translator.NormalizationMarker"); err != nil {
+		return err
+	}
+	if err := checkDuplicates([]string{translator.SentenceLocalityMarker, translator.ParagraphLocalityMarker}, "translator locality markers"); err != nil {
+		return err
+	}
+	if err := checkDuplicates([]string{translator.LosslessMarker}, "translator.LosslessMarker"); err != nil {
+		return err
+	}
+	if err := checkDuplicates([]string{translator.RulesetVersionMarker}, "translator.RulesetVersionMarker"); err != nil {
+		return err
+	}
+	if err := checkDuplicates(translator.RulesetVersionDigitIndex, "translator.RulesetVersionDigitIndex"); err != nil {
+		return err
+	}
+	if err := checkDuplicates([]string{translator.MetadataMarker}, "translator.MetadataMarker"); err != nil {
+		return err
+	}
+	if err := checkDuplicates(translator.MetadataDigitIndex, "translator.MetadataDigitIndex"); err != nil {
+		return err
+	}
 
 	// 5. Validate escape characters are not in special char indices
 	if _, exists := allSpecialChars[string(translator.InternalEscapeChar)]; exists {
@@ -316,6 +844,12 @@ func validateConstants() error {
 	if len(translator.MinuteSpecialCharIndex) != 60 {
 		return fmt.Errorf("translator.MinuteSpecialCharIndex must have exactly 60 elements, got %d", len(translator.MinuteSpecialCharIndex))
 	}
+	if len(translator.TimestampV2DigitIndex) != 16 {
+		return fmt.Errorf("translator.TimestampV2DigitIndex must have exactly 16 elements, got %d", len(translator.TimestampV2DigitIndex))
+	}
+	if len(translator.SignatureDigitIndex) != 16 {
+		return fmt.Errorf("translator.SignatureDigitIndex must have exactly 16 elements, got %d", len(translator.SignatureDigitIndex))
+	}
 
 	// 9. Validate translator.PunctuationMap bijectivity (no duplicate values)
 	punctuationMapValues := make(map[string]string) // map[value]key
@@ -362,39 +896,52 @@ func validateConstants() error {
 		return fmt.Errorf("translator.InternalEscapeChar and translator.OutputEscapeChar must be different, both are %q", translator.InternalEscapeChar)
 	}
 
-	// 13. Validate special chars don't overlap with translator.LetterMap or translator.ConjunctionMap
-	// Build set of all letters used in translator.LetterMap (keys and values)
+	// 13. Validate special chars don't overlap with the letter or conjunction maps of any script
+	// Build set of all letters used in the letter maps (keys and values)
 	letterMapChars := make(map[rune]bool)
-	for key, value := range translator.LetterMap {
-		for _, r := range key {
-			letterMapChars[r] = true
-		}
-		for _, r := range value {
-			letterMapChars[r] = true
+	for _, letterMap := range []map[string]string{translator.LetterMap, translator.CyrillicLetterMap, translator.GreekLetterMap} {
+		for key, value := range letterMap {
+			for _, r := range key {
+				letterMapChars[r] = true
+			}
+			for _, r := range value {
+				letterMapChars[r] = true
+			}
 		}
 	}
-	// Build set of all characters used in translator.ConjunctionMap (keys and values)
+	// Build set of all characters used in the conjunction maps (keys and values)
 	conjunctionMapChars := make(map[rune]bool)
-	for key, value := range translator.ConjunctionMap {
-		for _, r := range key {
-			conjunctionMapChars[r] = true
-		}
-		for _, r := range value {
-			conjunctionMapChars[r] = true
+	for _, conjunctionMap := range []map[string]string{translator.ConjunctionMap, translator.CyrillicConjunctionMap, translator.GreekConjunctionMap} {
+		for key, value := range conjunctionMap {
+			for _, r := range key {
+				conjunctionMapChars[r] = true
+			}
+			for _, r := range value {
+				conjunctionMapChars[r] = true
+			}
 		}
 	}
-	// Check special char indices don't contain translator.LetterMap or translator.ConjunctionMap characters
+	// Check special char indices don't contain letter or conjunction map characters
 	for char, source := range allSpecialChars {
 		for _, r := range char {
 			if letterMapChars[r] {
-				return fmt.Errorf("special character %q in %s conflicts with translator.LetterMap character %q", char, source, r)
+				return fmt.Errorf("special character %q in %s conflicts with letter map character %q", char, source, r)
 			}
 			if conjunctionMapChars[r] {
-				return fmt.Errorf("special character %q in %s conflicts with translator.ConjunctionMap character %q", char, source, r)
+				return fmt.Errorf("special character %q in %s conflicts with conjunction map character %q", char, source, r)
 			}
 		}
 	}
 
+	// 14. Validate the remaining round-trip rules of the dialect; its collisions are only reported,
+	// the built-in values keep theirs so that existing texts decode
+	if err := translator.CurrentRuleset().Validate(); err != nil {
+		return err
+	}
+	if err := translator.CurrentRuleset().ValidateStrict(); err != nil && !config.Obfuscated() {
+		log.Printf("Warning: %v", err)
+	}
+
 	return nil
 }
 
@@ -508,14 +1055,6 @@ func main() {
 		flag.Usage = func() {}
 	}
 
-	// Validate all constants before starting the server
-	if err := validateConstants(); err != nil {
-		log.Fatalf("Constants validation failed: %v", err)
-	}
-	if !config.Obfuscated() {
-		log.Println("Constants validation passed ✓")
-	}
-
 	// Parse command-line flags
 	var ngrokToken *string
 	var ngrokDomain *string
@@ -537,9 +1076,77 @@ func main() {
 
 	pronunciationLangFlag := flag.String("pronunciation_language", "russian", getFlagUsage("TTS pronunciation language (russian, portuguese, romanian, czech)"))
 	pronunciationLangDropdownFlag := flag.Bool("pronunciation_language_dropdown", true, getFlagUsage("Show language dropdown in UI for TTS"))
+	rulesetFlag := flag.String("ruleset", "", getFlagUsage("Optional JSON/YAML ruleset file defining a Pejelagarto dialect"))
+	streamFlag := flag.String("stream", "", getFlagUsage("Translate stdin to stdout in framed chunks and exit (\"to\" or \"from\" Pejelagarto)"))
+	signingKeyFileFlag := flag.String("signing_key_file", "", getFlagUsage("Optional file holding a shared secret that signs /to output and enables /from?verify=1"))
+	rulesetHistoryFlag := flag.String("ruleset_history", "", getFlagUsage("Optional directory of older JSON/YAML ruleset files whose texts must still decode"))
+	archiveRulesetFlag := flag.String("archive_ruleset", "", getFlagUsage("Write the current dialect to this directory as <fingerprint>.json and exit"))
+	migrateFlag := flag.Bool("migrate", false, getFlagUsage("Re-encode Pejelagarto from stdin into the current dialect on stdout and exit"))
+	migrateFromFlag := flag.String("migrate_from", "", getFlagUsage("Ruleset file used by -migrate for texts that carry no ruleset version, instead of the dialect of the texts written before versions"))
 
 	flag.Parse()
 
+	// Load the dialect before validating, so the checks below cover the rules actually in use
+	if *rulesetFlag != "" {
+		ruleset, err := translator.LoadRuleset(*rulesetFlag)
+		if err != nil {
+			log.Fatalf("Failed to load ruleset: %v", err)
+		}
+		if err := translator.UseRuleset(ruleset); err != nil {
+			log.Fatalf("Failed to apply ruleset: %v", err)
+		}
+		if !config.Obfuscated() {
+			log.Printf("Using ruleset %q from %s", ruleset.Name, *rulesetFlag)
+		}
+	}
+
+	if *rulesetHistoryFlag != "" {
+		if err := translator.LoadRulesetHistory(*rulesetHistoryFlag); err != nil {
+			log.Fatalf("Failed to load ruleset history: %v", err)
+		}
+	}
+
+	if *signingKeyFileFlag != "" {
+		key, err := os.ReadFile(*signingKeyFileFlag)
+		if err != nil {
+			log.Fatalf("Failed to read signing key: %v", err)
+		}
+		signingKey = []byte(strings.TrimSpace(string(key)))
+		if len(signingKey) == 0 {
+			log.Fatalf("Signing key file %s is empty", *signingKeyFileFlag)
+		}
+	}
+
+	// Validate all constants before starting the server
+	if err := validateConstants(); err != nil {
+		log.Fatalf("Constants validation failed: %v", err)
+	}
+	if !config.Obfuscated() {
+		log.Println("Constants validation passed ✓")
+	}
+
+	// CLI mode: translate stdin to stdout without starting the server
+	if *streamFlag != "" {
+		if err := runStreamTranslation(*streamFlag); err != nil {
+			log.Fatalf("Stream translation failed: %v", err)
+		}
+		return
+	}
+	if *archiveRulesetFlag != "" {
+		file, err := translator.ArchiveRuleset(translator.CurrentRuleset(), *archiveRulesetFlag)
+		if err != nil {
+			log.Fatalf("Failed to archive ruleset: %v", err)
+		}
+		log.Printf("Archived the current dialect to %s", file)
+		return
+	}
+	if *migrateFlag {
+		if err := runMigration(*migrateFromFlag); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	if !strings.HasPrefix(*ngrokDomain, "http://") && !strings.HasPrefix(*ngrokDomain, "https://") {
 		*ngrokDomain = "https://" + *ngrokDomain
 	}
@@ -582,6 +1189,11 @@ func main() {
 	http.HandleFunc("/", handleIndex)
 	http.HandleFunc("/to", handleTranslateTo)
 	http.HandleFunc("/from", handleTranslateFrom)
+	http.HandleFunc("/auto", handleTranslateAuto)
+	http.HandleFunc("/recover", handleRecover)
+	http.HandleFunc("/stream/to", handleStreamTo)
+	http.HandleFunc("/stream/from", handleStreamFrom)
+	http.HandleFunc("/glossary", handleGlossary)
 	http.HandleFunc("/tts", tts.HandleTextToSpeech)
 	http.HandleFunc("/tts-check-slow", tts.HandleCheckSlowAudio)
 	http.HandleFunc("/api/is-downloadable", handleIsDownloadable)
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"net/url"
 	"syscall/js"
 
 	"pejelagarto-translator/internal/translator"
@@ -23,24 +24,52 @@ func main() {
 	select {}
 }
 
+// optionsFromJS converts an optional JavaScript options object into translator options
+// Keys and values follow the HTTP query parameters, e.g. {accents: false, timestamp: "2025-10-19T14:30:00Z"}
+func optionsFromJS(args []js.Value) (translator.Options, error) {
+	if len(args) < 2 || args[1].IsUndefined() || args[1].IsNull() {
+		return translator.Options{}, nil
+	}
+
+	values := url.Values{}
+	keys := js.Global().Get("Object").Call("keys", args[1])
+	for i := 0; i < keys.Length(); i++ {
+		key := keys.Index(i).String()
+		values.Set(key, args[1].Get(key).String())
+	}
+	return translator.ParseOptions(values)
+}
+
 // goTranslateToPejalagartoJS wraps TranslateToPejelagarto for JavaScript
+// An optional second argument holds translation options
 func goTranslateToPejalagartoJS(this js.Value, args []js.Value) interface{} {
-	if len(args) != 1 {
-		return js.ValueOf("Error: expected 1 argument")
+	if len(args) != 1 && len(args) != 2 {
+		return js.ValueOf("Error: expected 1 or 2 arguments")
+	}
+
+	opts, err := optionsFromJS(args)
+	if err != nil {
+		return js.ValueOf("Error: " + err.Error())
 	}
 
 	input := args[0].String()
-	result := translator.TranslateToPejelagarto(input)
+	result := translator.New(opts).ToPejelagarto(input)
 	return js.ValueOf(result)
 }
 
 // goTranslateFromPejalagartoJS wraps TranslateFromPejelagarto for JavaScript
+// An optional second argument holds translation options
 func goTranslateFromPejalagartoJS(this js.Value, args []js.Value) interface{} {
-	if len(args) != 1 {
-		return js.ValueOf("Error: expected 1 argument")
+	if len(args) != 1 && len(args) != 2 {
+		return js.ValueOf("Error: expected 1 or 2 arguments")
+	}
+
+	opts, err := optionsFromJS(args)
+	if err != nil {
+		return js.ValueOf("Error: " + err.Error())
 	}
 
 	input := args[0].String()
-	result := translator.TranslateFromPejelagarto(input)
+	result := translator.New(opts).FromPejelagarto(input)
 	return js.ValueOf(result)
 }
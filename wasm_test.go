@@ -118,7 +118,7 @@ func TestJSWrapperArgumentValidation(t *testing.T) {
 	t.Run("goTranslateToPejalagartoJS with no args", func(t *testing.T) {
 		result := goTranslateToPejalagartoJS(js.Null(), []js.Value{})
 		resultStr := result.(js.Value).String()
-		if resultStr != "Error: expected 1 argument" {
+		if resultStr != "Error: expected 1 or 2 arguments" {
 			t.Errorf("Expected error message, got: %q", resultStr)
 		}
 	})
@@ -126,7 +126,7 @@ func TestJSWrapperArgumentValidation(t *testing.T) {
 	t.Run("goTranslateFromPejalagartoJS with no args", func(t *testing.T) {
 		result := goTranslateFromPejalagartoJS(js.Null(), []js.Value{})
 		resultStr := result.(js.Value).String()
-		if resultStr != "Error: expected 1 argument" {
+		if resultStr != "Error: expected 1 or 2 arguments" {
 			t.Errorf("Expected error message, got: %q", resultStr)
 		}
 	})